@@ -2,6 +2,7 @@ package main
 
 import (
 	"math/rand"
+	"runtime"
 	"sync"
 	"testing"
 
@@ -68,7 +69,7 @@ func benchmarkParsing(b *testing.B, tsFoundry tagset.Foundry) {
 	const warmupCount = 1000
 	tlg := loadgen.NewCmdTagLineGenerator("dsd", n+warmupCount)
 	lines := tlg.GetLines()
-	idFoundry := ident.NewInternFoundry()
+	idFoundry := ident.Must(ident.NewInternFoundry())
 
 	// warm up the parser first
 	for i := 0; i < warmupCount; i++ {
@@ -102,14 +103,20 @@ func benchmarkParsing(b *testing.B, tsFoundry tagset.Foundry) {
 	require.Equal(b, count, n)
 }
 
-func BenchmarkNullFoundryParsing(b *testing.B) { benchmarkParsing(b, tagset.NewNullFoundry()) }
+func BenchmarkNullFoundryParsing(b *testing.B) {
+	benchmarkParsing(b, tagset.Must(tagset.NewNullFoundry()))
+}
 func BenchmarkInternFoundryParsing(b *testing.B) {
-	f := tagset.NewInternFoundry()
+	f, err := tagset.NewInternFoundry()
+	require.NoError(b, err)
 	benchmarkParsing(b, f)
 	// report the percent of parses that missed the cache
 	b.ReportMetric(float64(f.ParseMisses)*100/float64(f.Parses), "miss%")
 }
 
+// benchmarkUnion measures Union on random pairs of tagsets parsed by the
+// given foundry.  See tagset's BenchmarkUnionDSD for a comparison with the
+// nested-loop Union used before tags were kept sorted.
 func benchmarkUnion(b *testing.B, tsFoundry tagset.Foundry) {
 	// operate at 1000x the benchmarks, because otherwise allocs/op rounds
 	// to the nearest integer and loses precision
@@ -123,7 +130,7 @@ func benchmarkUnion(b *testing.B, tsFoundry tagset.Foundry) {
 	tagsets := make([]*tagset.TagSet, 0, tagsetCount)
 	tlg := loadgen.NewCmdTagLineGenerator("dsd", tagsetCount)
 	lines := tlg.GetLines()
-	idFoundry := ident.NewInternFoundry()
+	idFoundry := ident.Must(ident.NewInternFoundry())
 	for i := 0; i < tagsetCount; i++ {
 		tagsets = append(tagsets, tsFoundry.Parse(idFoundry, <-lines))
 	}
@@ -143,5 +150,127 @@ func benchmarkUnion(b *testing.B, tsFoundry tagset.Foundry) {
 	test(n)
 }
 
-func BenchmarkNullFoundryUnion(b *testing.B)   { benchmarkUnion(b, tagset.NewNullFoundry()) }
-func BenchmarkInternFoundryUnion(b *testing.B) { benchmarkUnion(b, tagset.NewInternFoundry()) }
+func BenchmarkNullFoundryUnion(b *testing.B) { benchmarkUnion(b, tagset.Must(tagset.NewNullFoundry())) }
+
+func BenchmarkInternFoundryUnion(b *testing.B) {
+	benchmarkUnion(b, tagset.Must(tagset.NewInternFoundry()))
+}
+func BenchmarkInternFoundryCachedUnion(b *testing.B) {
+	f, err := tagset.NewInternFoundry(tagset.WithUnionCacheSize(10000))
+	require.NoError(b, err)
+	benchmarkUnion(b, f)
+	// report the percent of unions that missed the cache
+	b.ReportMetric(float64(f.UnionMisses)*100/float64(f.Unions), "miss%")
+}
+
+// benchmarkUnionPipeline measures a union-heavy pipeline, in which several
+// unions are performed and only the final result is serialized.  This
+// demonstrates the savings from lazy serialization.
+func benchmarkUnionPipeline(b *testing.B, tsFoundry tagset.Foundry) {
+	const tagsetCount = 100
+	const unions = 4
+	tagsets := make([]*tagset.TagSet, 0, tagsetCount)
+	tlg := loadgen.NewCmdTagLineGenerator("dsd", tagsetCount)
+	lines := tlg.GetLines()
+	idFoundry := ident.Must(ident.NewInternFoundry())
+	for i := 0; i < tagsetCount; i++ {
+		tagsets = append(tagsets, tsFoundry.Parse(idFoundry, <-lines))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		ts := tagsets[rand.Intn(tagsetCount)]
+		for j := 0; j < unions; j++ {
+			ts = tsFoundry.Union(ts, tagsets[rand.Intn(tagsetCount)])
+		}
+		Line = ts.Serialization()
+	}
+}
+
+func BenchmarkLazyUnionPipeline(b *testing.B) {
+	benchmarkUnionPipeline(b, tagset.Must(tagset.NewNullFoundry(tagset.WithSerialization(tagset.LazySerialization))))
+}
+func BenchmarkEagerUnionPipeline(b *testing.B) {
+	benchmarkUnionPipeline(b, tagset.Must(tagset.NewNullFoundry(tagset.WithSerialization(tagset.EagerSerialization))))
+}
+
+// benchmarkUnion3 measures the README's core scenario of combining three
+// tagsets, either with two calls to Union or one call to UnionN.
+func benchmarkUnion3(b *testing.B, unionN bool) {
+	tsFoundry := tagset.Must(tagset.NewNullFoundry(tagset.WithSerialization(tagset.EagerSerialization)))
+	const tagsetCount = 100
+	tagsets := make([]*tagset.TagSet, 0, tagsetCount)
+	tlg := loadgen.NewCmdTagLineGenerator("dsd", tagsetCount)
+	lines := tlg.GetLines()
+	idFoundry := ident.Must(ident.NewInternFoundry())
+	for i := 0; i < tagsetCount; i++ {
+		tagsets = append(tagsets, tsFoundry.Parse(idFoundry, <-lines))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		hostTags := tagsets[rand.Intn(tagsetCount)]
+		sampleTags := tagsets[rand.Intn(tagsetCount)]
+		taggerTags := tagsets[rand.Intn(tagsetCount)]
+		if unionN {
+			TS = tsFoundry.UnionN(hostTags, sampleTags, taggerTags)
+		} else {
+			TS = tsFoundry.Union(tsFoundry.Union(hostTags, sampleTags), taggerTags)
+		}
+	}
+}
+
+func BenchmarkUnion3(b *testing.B)  { benchmarkUnion3(b, false) }
+func BenchmarkUnionN3(b *testing.B) { benchmarkUnion3(b, true) }
+
+// global place for GC benchmarks to retain data
+var Retained interface{}
+
+// benchmarkGC measures the time taken for a full garbage collection while
+// holding a large number of parsed tagsets, as returned from `parseAll`.
+func benchmarkGC(b *testing.B, parseAll func(lines chan []byte) interface{}) {
+	const tagsetCount = 100000
+	tlg := loadgen.NewCmdTagLineGenerator("dsd", tagsetCount)
+	Retained = parseAll(tlg.GetLines())
+	runtime.GC()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+	}
+
+	b.StopTimer()
+	Retained = nil
+}
+
+func BenchmarkTagSetGC(b *testing.B) {
+	benchmarkGC(b, func(lines chan []byte) interface{} {
+		idFoundry := ident.Must(ident.NewInternFoundry())
+		tsFoundry := tagset.Must(tagset.NewNullFoundry())
+		tagsets := []*tagset.TagSet{}
+		for line := range lines {
+			tagsets = append(tagsets, tsFoundry.Parse(idFoundry, line))
+		}
+		return tagsets
+	})
+}
+
+func BenchmarkHandleSetGC(b *testing.B) {
+	benchmarkGC(b, func(lines chan []byte) interface{} {
+		arena, err := ident.NewArenaFoundry()
+		require.NoError(b, err)
+		hsFoundry, err := tagset.NewArenaFoundry(arena)
+		require.NoError(b, err)
+		handlesets := []*tagset.HandleSet{}
+		for line := range lines {
+			handlesets = append(handlesets, hsFoundry.Parse(line))
+		}
+		return handlesets
+	})
+}
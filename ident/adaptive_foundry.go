@@ -0,0 +1,255 @@
+package ident
+
+import "fmt"
+
+/* IMPLEMENTATION NOTES
+ *
+ * An AdaptiveFoundry measures its hit rate over windows of `window` accesses,
+ * and moves between three modes, from most to least retentive:
+ *
+ *  - Intern: identifiers are interned forever, like an InternFoundry
+ *  - Revolving: identifiers are interned in generations which rotate at the
+ *    end of each window, like a RevolvingFoundry
+ *  - Null: identifiers are not interned, like a NullFoundry
+ *
+ * It moves to a less retentive mode ("demotes") after `adaptivePatience`
+ * consecutive windows with a hit rate below `adaptiveLowHitRate`, and to a
+ * more retentive mode ("promotes") after as many windows with a hit rate
+ * above `adaptiveHighHitRate`.  The gap between the thresholds, and the
+ * required run of windows, prevent flapping between modes.
+ *
+ * Independently of hit rate, the Intern mode demotes immediately when it holds
+ * more than `maxEntries` identifiers, and the Revolving mode only promotes
+ * when it holds fewer than half that many.  This bounds the memory that a
+ * single misbehaving source of identifiers can consume.  A `maxEntries` of 0
+ * disables the bound.
+ *
+ * In Null mode there is nothing to hit, so the hit rate is estimated by
+ * tracking the hashes of the identifiers seen in the current window.
+ */
+
+// Thresholds for changing modes in an AdaptiveFoundry
+const (
+	adaptiveLowHitRate  = 0.1
+	adaptiveHighHitRate = 0.5
+	adaptivePatience    = 3
+	adaptiveGenerations = 3
+)
+
+// An AdaptiveMode describes the current behavior of an AdaptiveFoundry.
+type AdaptiveMode int
+
+const (
+	// AdaptiveIntern interns identifiers forever, like InternFoundry
+	AdaptiveIntern AdaptiveMode = iota
+	// AdaptiveRevolving interns identifiers in rotating generations, like
+	// RevolvingFoundry
+	AdaptiveRevolving
+	// AdaptiveNull does not intern identifiers, like NullFoundry
+	AdaptiveNull
+)
+
+func (m AdaptiveMode) String() string {
+	switch m {
+	case AdaptiveIntern:
+		return "intern"
+	case AdaptiveRevolving:
+		return "revolving"
+	case AdaptiveNull:
+		return "null"
+	default:
+		return fmt.Sprintf("AdaptiveMode(%d)", int(m))
+	}
+}
+
+// An AdaptiveFoundry switches between interning strategies based on its
+// observed hit rate, so that high-cardinality identifiers do not consume
+// memory without benefit.  An AdaptiveFoundry is not threadsafe.
+//
+// The statistics fields describe the foundry's behavior and decisions.
+type AdaptiveFoundry struct {
+	capacity   int
+	window     int
+	maxEntries int
+
+	mode        AdaptiveMode
+	generations []*InternFoundry
+	sample      map[[2]uint64]struct{}
+
+	// accesses and hits in the current window
+	windowCount, windowHits int
+
+	// consecutive windows with low or high hit rates
+	lowStreak, highStreak int
+
+	// Count of identifiers found in the foundry (or, in Null mode, seen
+	// earlier in the same window), and not found
+	Hits, Misses uint64
+
+	// Count of completed windows, and the hit rate in the most recent
+	Windows     uint64
+	LastHitRate float64
+
+	// Count of moves to more retentive (promotions) and less retentive
+	// (demotions) modes
+	Promotions, Demotions uint64
+}
+
+// Create an AdaptiveFoundry which samples its hit rate every WithWindow
+// accesses (default 10,000), and holds at most about WithMaxEntries
+// identifiers (default 1,000,000).  WithCapacity pre-sizes each generation.
+// It begins in the Intern mode.
+func NewAdaptiveFoundry(opts ...Option) (*AdaptiveFoundry, error) {
+	o, err := applyOptions(opts, optCapacity, optWindow, optMaxEntries)
+	if err != nil {
+		return nil, err
+	}
+	return &AdaptiveFoundry{
+		capacity:    o.capacity,
+		window:      o.window,
+		maxEntries:  o.maxEntries,
+		mode:        AdaptiveIntern,
+		generations: []*InternFoundry{newInternFoundry(o.capacity)},
+	}, nil
+}
+
+// Mode returns the current mode of the foundry.
+func (f *AdaptiveFoundry) Mode() AdaptiveMode {
+	return f.mode
+}
+
+func (f *AdaptiveFoundry) Ident(ident []byte) Ident {
+	hashH, hashL := hashIdent(ident)
+
+	var rv Ident
+	var hit bool
+	if f.mode == AdaptiveNull {
+		key := [2]uint64{hashH, hashL}
+		if _, hit = f.sample[key]; !hit {
+			f.sample[key] = struct{}{}
+		}
+		rv = newIdent(ident, hashH, hashL)
+	} else {
+		rv = f.Get(hashH, hashL)
+		hit = rv != nil
+		if !hit {
+			rv = newIdent(ident, hashH, hashL)
+		}
+		// (re-)insert into the first generation, so that it survives
+		// rotation
+		if !hit || f.generations[0].get(hashH, hashL) == nil {
+			f.generations[0].insert(hashH, hashL, rv)
+		}
+	}
+
+	f.windowCount++
+	if hit {
+		f.Hits++
+		f.windowHits++
+	} else {
+		f.Misses++
+	}
+
+	if f.mode == AdaptiveIntern && f.maxEntries > 0 && f.entries() > f.maxEntries {
+		f.setMode(AdaptiveRevolving)
+	}
+
+	if f.windowCount >= f.window {
+		f.endWindow()
+	}
+
+	return rv
+}
+
+// Get returns the interned Ident with the given hash, or nil if there is none.
+func (f *AdaptiveFoundry) Get(hashH, hashL uint64) Ident {
+	for _, gen := range f.generations {
+		if hit := gen.get(hashH, hashL); hit != nil {
+			return hit
+		}
+	}
+	return nil
+}
+
+// entries counts the identifiers held in the foundry (counting those present
+// in multiple generations multiple times)
+func (f *AdaptiveFoundry) entries() int {
+	count := 0
+	for _, gen := range f.generations {
+		count += gen.byHash.len()
+	}
+	return count
+}
+
+// endWindow calculates the hit rate for the window just completed, and
+// decides whether to change mode.
+func (f *AdaptiveFoundry) endWindow() {
+	rate := float64(f.windowHits) / float64(f.windowCount)
+	f.Windows++
+	f.LastHitRate = rate
+	f.windowCount, f.windowHits = 0, 0
+
+	if rate < adaptiveLowHitRate {
+		f.lowStreak++
+		f.highStreak = 0
+	} else if rate > adaptiveHighHitRate {
+		f.highStreak++
+		f.lowStreak = 0
+	} else {
+		f.lowStreak, f.highStreak = 0, 0
+	}
+
+	switch {
+	case f.lowStreak >= adaptivePatience && f.mode != AdaptiveNull:
+		f.setMode(f.mode + 1)
+	case f.highStreak >= adaptivePatience && f.mode == AdaptiveNull:
+		f.setMode(AdaptiveRevolving)
+	case f.highStreak >= adaptivePatience && f.mode == AdaptiveRevolving &&
+		(f.maxEntries == 0 || f.entries() < f.maxEntries/2):
+		f.setMode(AdaptiveIntern)
+	case f.mode == AdaptiveRevolving:
+		f.rotate()
+	case f.mode == AdaptiveNull:
+		f.sample = map[[2]uint64]struct{}{}
+	}
+}
+
+// setMode switches to the given mode, counting the decision.
+func (f *AdaptiveFoundry) setMode(mode AdaptiveMode) {
+	if mode < f.mode {
+		f.Promotions++
+	} else {
+		f.Demotions++
+	}
+	f.mode = mode
+	f.lowStreak, f.highStreak = 0, 0
+
+	switch mode {
+	case AdaptiveIntern:
+		// keep all existing generations, but stop rotating
+	case AdaptiveRevolving:
+		if f.generations == nil {
+			f.generations = []*InternFoundry{newInternFoundry(f.capacity)}
+		} else {
+			f.rotate()
+		}
+		f.sample = nil
+	case AdaptiveNull:
+		f.generations = nil
+		f.sample = map[[2]uint64]struct{}{}
+	}
+}
+
+// rotate inserts a new generation, dropping the oldest generation if there
+// are more than adaptiveGenerations.
+func (f *AdaptiveFoundry) rotate() {
+	gens := make([]*InternFoundry, 0, adaptiveGenerations)
+	gens = append(gens, newInternFoundry(f.capacity))
+	for _, gen := range f.generations {
+		if len(gens) == adaptiveGenerations {
+			break
+		}
+		gens = append(gens, gen)
+	}
+	f.generations = gens
+}
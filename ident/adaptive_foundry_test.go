@@ -0,0 +1,111 @@
+package ident
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptiveFoundryLowCardinality(t *testing.T) {
+	f, err := NewAdaptiveFoundry(WithWindow(100), WithMaxEntries(1000))
+	require.NoError(t, err)
+
+	var first Ident
+	for i := 0; i < 10000; i++ {
+		id := f.Ident([]byte(fmt.Sprintf("tag:%d", i%10)))
+		if i == 0 {
+			first = id
+		} else if i%10 == 0 {
+			require.True(t, &first[0] == &id[0])
+		}
+	}
+
+	require.Equal(t, AdaptiveIntern, f.Mode())
+	require.Equal(t, uint64(10), f.Misses)
+	require.Equal(t, uint64(9990), f.Hits)
+	require.Equal(t, uint64(100), f.Windows)
+	require.Equal(t, 1.0, f.LastHitRate)
+	require.Equal(t, uint64(0), f.Demotions)
+}
+
+func TestAdaptiveFoundryHighCardinality(t *testing.T) {
+	f, err := NewAdaptiveFoundry(WithWindow(100), WithMaxEntries(100000))
+	require.NoError(t, err)
+
+	// every identifier is unique, so after enough low-hit-rate windows the
+	// foundry demotes, first to revolving..
+	for i := 0; i < 300; i++ {
+		f.Ident([]byte(fmt.Sprintf("tag:%d", i)))
+	}
+	require.Equal(t, AdaptiveRevolving, f.Mode())
+	require.Equal(t, uint64(1), f.Demotions)
+
+	// ..and then stops interning
+	for i := 300; i < 600; i++ {
+		f.Ident([]byte(fmt.Sprintf("tag:%d", i)))
+	}
+	require.Equal(t, AdaptiveNull, f.Mode())
+	require.Equal(t, uint64(2), f.Demotions)
+	require.Equal(t, 0.0, f.LastHitRate)
+
+	// in null mode, nothing is interned
+	id1 := f.Ident([]byte("tag:z"))
+	id2 := f.Ident([]byte("tag:z"))
+	require.True(t, &id1[0] != &id2[0])
+	require.Nil(t, f.Get(id1.Hash()))
+}
+
+func TestAdaptiveFoundryMaxEntries(t *testing.T) {
+	f, err := NewAdaptiveFoundry(WithWindow(100), WithMaxEntries(1000))
+	require.NoError(t, err)
+
+	// each identifier is seen twice, for a 50% hit rate, which does not
+	// change modes
+	for i := 0; i < 1000; i++ {
+		f.Ident([]byte(fmt.Sprintf("tag:%d", i)))
+		f.Ident([]byte(fmt.Sprintf("tag:%d", i)))
+	}
+	require.Equal(t, AdaptiveIntern, f.Mode())
+	require.Equal(t, 0.5, f.LastHitRate)
+
+	// but exceeding maxEntries demotes immediately
+	f.Ident([]byte("tag:x"))
+	require.Equal(t, AdaptiveRevolving, f.Mode())
+	require.Equal(t, uint64(1), f.Demotions)
+}
+
+func TestAdaptiveFoundryRecovery(t *testing.T) {
+	f, err := NewAdaptiveFoundry(WithWindow(100), WithMaxEntries(1000))
+	require.NoError(t, err)
+
+	for i := 0; i < 2000; i++ {
+		f.Ident([]byte(fmt.Sprintf("tag:%d", i)))
+	}
+	require.Equal(t, AdaptiveNull, f.Mode())
+
+	// a run of low-cardinality identifiers promotes back to revolving..
+	for i := 0; i < 400; i++ {
+		f.Ident([]byte(fmt.Sprintf("low:%d", i%5)))
+	}
+	require.Equal(t, AdaptiveRevolving, f.Mode())
+	require.Equal(t, uint64(1), f.Promotions)
+
+	// ..and then to intern
+	for i := 0; i < 400; i++ {
+		f.Ident([]byte(fmt.Sprintf("low:%d", i%5)))
+	}
+	require.Equal(t, AdaptiveIntern, f.Mode())
+	require.Equal(t, uint64(2), f.Promotions)
+
+	id1 := f.Ident([]byte("low:1"))
+	id2 := f.Ident([]byte("low:1"))
+	require.True(t, &id1[0] == &id2[0])
+}
+
+func TestAdaptiveModeString(t *testing.T) {
+	require.Equal(t, "intern", AdaptiveIntern.String())
+	require.Equal(t, "revolving", AdaptiveRevolving.String())
+	require.Equal(t, "null", AdaptiveNull.String())
+	require.Equal(t, "AdaptiveMode(7)", AdaptiveMode(7).String())
+}
@@ -0,0 +1,208 @@
+package ident
+
+import "github.com/djmitche/tagset/internal/hashtable"
+
+/* IMPLEMENTATION NOTES
+ *
+ * An ArenaFoundry stores identifiers without giving each its own heap
+ * allocation.  Identifier bytes are appended to large, fixed-size chunks, and
+ * each identifier is described by an `arenaEntry` holding its hash and
+ * location.  A Handle is an index into the slice of entries.
+ *
+ * None of the per-identifier data structures contain pointers: chunks are
+ * byte slices, entries are plain integers, and the hash table stores handles.
+ * The garbage collector therefore need not scan them, no matter how many
+ * identifiers are stored.  The only pointers are the chunk slice headers, one
+ * per `arenaChunkSize` bytes.
+ *
+ * Handle 0 is never assigned, and marks an empty slot in the hash table.
+ */
+
+// arenaChunkSize is the size of each chunk of identifier storage.  Identifiers
+// larger than this are given a chunk of their own.
+const arenaChunkSize = 64 * 1024
+
+// A Handle refers to an identifier interned in an ArenaFoundry.  Handles are
+// only meaningful to the foundry that created them.  Two handles from the
+// same foundry are equal exactly when their identifiers are equal.
+type Handle uint32
+
+// An ArenaFoundry interns identifiers in a pointer-free arena, returning
+// Handles instead of Idents.  Like InternFoundry, it caches identifiers
+// forever.  An ArenaFoundry is not threadsafe.
+type ArenaFoundry struct {
+	chunks  [][]byte
+	cur     int
+	entries []arenaEntry
+	byHash  handleTable
+}
+
+type arenaEntry struct {
+	hashH, hashL  uint64
+	chunk, offset uint32
+	length        uint32
+}
+
+// Create an ArenaFoundry.  The WithCapacity option pre-sizes its tables.
+func NewArenaFoundry(opts ...Option) (*ArenaFoundry, error) {
+	o, err := applyOptions(opts, optCapacity)
+	if err != nil {
+		return nil, err
+	}
+
+	// entry 0 is reserved so that the zero Handle is never valid
+	entries := make([]arenaEntry, 1, o.capacity+1)
+
+	return &ArenaFoundry{
+		cur:     -1,
+		entries: entries,
+		byHash:  newHandleTable(o.capacity),
+	}, nil
+}
+
+// Handle returns a Handle for the given byte slice.  The byte slice is not
+// maintained, and the caller may reuse it.
+func (f *ArenaFoundry) Handle(ident []byte) Handle {
+	hashH, hashL := hashIdent(ident)
+	existing := f.byHash.get(hashH, hashL, f.entries)
+	if existing != 0 {
+		return existing
+	}
+
+	chunk, offset := f.alloc(len(ident))
+	copy(f.chunks[chunk][offset:], ident)
+
+	h := Handle(len(f.entries))
+	f.entries = append(f.entries, arenaEntry{
+		hashH:  hashH,
+		hashL:  hashL,
+		chunk:  uint32(chunk),
+		offset: uint32(offset),
+		length: uint32(len(ident)),
+	})
+	f.byHash.insert(hashL, h)
+
+	return h
+}
+
+// Bytes returns the bytes defining the identifier.  The returned value MUST
+// not be modified.
+func (f *ArenaFoundry) Bytes(h Handle) []byte {
+	e := &f.entries[h]
+	return f.chunks[e.chunk][e.offset : e.offset+e.length : e.offset+e.length]
+}
+
+// Hash returns the 128-bit hash of the identifier, high word first
+func (f *ArenaFoundry) Hash(h Handle) (uint64, uint64) {
+	e := &f.entries[h]
+	return e.hashH, e.hashL
+}
+
+// HashH returns the high hash of the identifier
+func (f *ArenaFoundry) HashH(h Handle) uint64 {
+	return f.entries[h].hashH
+}
+
+// HashL returns the low hash of the identifier
+func (f *ArenaFoundry) HashL(h Handle) uint64 {
+	return f.entries[h].hashL
+}
+
+// Less orders identifiers by their hashes, as Ident.Less does.
+func (f *ArenaFoundry) Less(h1, h2 Handle) bool {
+	e1, e2 := &f.entries[h1], &f.entries[h2]
+	return e1.hashH < e2.hashH || (e1.hashH == e2.hashH && e1.hashL < e2.hashL)
+}
+
+// Len returns the number of identifiers in the arena.
+func (f *ArenaFoundry) Len() int {
+	return len(f.entries) - 1
+}
+
+// alloc finds space for `size` bytes, returning the chunk index and offset
+// at which they can be written.
+func (f *ArenaFoundry) alloc(size int) (int, int) {
+	// oversized identifiers get a chunk of their own, leaving the current
+	// chunk to continue filling
+	if size > arenaChunkSize {
+		f.chunks = append(f.chunks, make([]byte, size))
+		return len(f.chunks) - 1, 0
+	}
+
+	if f.cur < 0 || len(f.chunks[f.cur])+size > cap(f.chunks[f.cur]) {
+		f.chunks = append(f.chunks, make([]byte, 0, arenaChunkSize))
+		f.cur = len(f.chunks) - 1
+	}
+
+	offset := len(f.chunks[f.cur])
+	f.chunks[f.cur] = f.chunks[f.cur][:offset+size]
+	return f.cur, offset
+}
+
+// A handleTable is an open-addressing hash table mapping 128-bit hashes to
+// Handles, structured like identTable but without pointers.
+type handleTable struct {
+	slots []handleSlot
+	mask  uint64
+	count int
+}
+
+type handleSlot struct {
+	hashL  uint64
+	handle Handle
+}
+
+func newHandleTable(capacity int) handleTable {
+	size := hashtable.SizeFor(capacity)
+	return handleTable{
+		slots: make([]handleSlot, size),
+		mask:  uint64(size - 1),
+	}
+}
+
+// get returns the handle with the given hash, or 0 if it is not present.  The
+// slots only store the low hash inline; the high hash is checked against the
+// entry.
+func (tbl *handleTable) get(hashH, hashL uint64, entries []arenaEntry) Handle {
+	for i := hashL & tbl.mask; ; i = (i + 1) & tbl.mask {
+		slot := &tbl.slots[i]
+		if slot.handle == 0 {
+			return 0
+		}
+		if slot.hashL == hashL && entries[slot.handle].hashH == hashH {
+			return slot.handle
+		}
+	}
+}
+
+// insert adds a handle to the table.  The caller must ensure it is not
+// already present.
+func (tbl *handleTable) insert(hashL uint64, h Handle) {
+	if hashtable.MustGrow(tbl.count, len(tbl.slots)) {
+		tbl.grow()
+	}
+
+	i := hashL & tbl.mask
+	for tbl.slots[i].handle != 0 {
+		i = (i + 1) & tbl.mask
+	}
+	tbl.slots[i] = handleSlot{hashL: hashL, handle: h}
+	tbl.count++
+}
+
+func (tbl *handleTable) grow() {
+	old := tbl.slots
+	tbl.slots = make([]handleSlot, len(old)*2)
+	tbl.mask = uint64(len(tbl.slots) - 1)
+
+	for _, slot := range old {
+		if slot.handle == 0 {
+			continue
+		}
+		i := slot.hashL & tbl.mask
+		for tbl.slots[i].handle != 0 {
+			i = (i + 1) & tbl.mask
+		}
+		tbl.slots[i] = slot
+	}
+}
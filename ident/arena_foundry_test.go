@@ -0,0 +1,73 @@
+package ident
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestArenaFoundry(t *testing.T) {
+	f, err := NewArenaFoundry()
+	require.NoError(t, err)
+
+	buf := []byte("aaa")
+	h1 := f.Handle(buf)
+	// overwrite the buffer, which should not affect the arena
+	copy(buf, "bbb")
+	h2 := f.Handle([]byte("aaa"))
+	h3 := f.Handle(buf)
+
+	require.Equal(t, h1, h2)
+	require.NotEqual(t, h1, h3)
+	require.NotEqual(t, Handle(0), h1)
+	require.Equal(t, 2, f.Len())
+
+	require.Equal(t, []byte("aaa"), f.Bytes(h1))
+	require.Equal(t, []byte("bbb"), f.Bytes(h3))
+
+	expH, expL := hashIdent([]byte("aaa"))
+	gotH, gotL := f.Hash(h1)
+	require.Equal(t, expH, gotH)
+	require.Equal(t, expL, gotL)
+	require.Equal(t, expH, f.HashH(h1))
+	require.Equal(t, expL, f.HashL(h1))
+}
+
+func TestArenaFoundryManyChunks(t *testing.T) {
+	f, err := NewArenaFoundry()
+	require.NoError(t, err)
+
+	// enough data to fill several chunks, including some oversized idents
+	var handles []Handle
+	var values [][]byte
+	for i := 0; i < 10000; i++ {
+		v := []byte(fmt.Sprintf("tag:%d:%s", i, bytes.Repeat([]byte{'x'}, i%50)))
+		if i%1000 == 0 {
+			v = append(v, bytes.Repeat([]byte{'y'}, arenaChunkSize+1)...)
+		}
+		handles = append(handles, f.Handle(v))
+		values = append(values, v)
+	}
+
+	for i, h := range handles {
+		require.Equal(t, values[i], f.Bytes(h))
+		require.Equal(t, h, f.Handle(values[i]))
+	}
+	require.Equal(t, 10000, f.Len())
+}
+
+func TestArenaFoundryLess(t *testing.T) {
+	f, err := NewArenaFoundry()
+	require.NoError(t, err)
+	ha := f.Handle([]byte("abc"))
+	hb := f.Handle([]byte("123"))
+
+	ia := makeIdent("abc")
+	ib := makeIdent("123")
+
+	require.Equal(t, ia.Less(ib), f.Less(ha, hb))
+	require.Equal(t, ib.Less(ia), f.Less(hb, ha))
+	require.False(t, f.Less(ha, ha))
+}
@@ -0,0 +1,120 @@
+package ident
+
+import "errors"
+
+/* IMPLEMENTATION NOTES
+ *
+ * A CachedFoundry is the first level (L1) of a two-level foundry, and is owned
+ * by a single goroutine.  The second level (L2) is a shared foundry, typically
+ * a ThreadsafeFoundry, which is consulted on L1 misses.  Because every Ident
+ * in L1 came from L2, Idents remain pointer-identical across all of the
+ * CachedFoundries sharing an L2, for as long as L2 retains them.
+ *
+ * Every L1 miss makes exactly one call to the backing foundry, IdentHit if it
+ * is a HitFoundry and otherwise Ident.  That call both counts the L2 hit or
+ * miss atomically and records the access, so a RevolvingFoundry promotes the
+ * Ident to its newest generation.  L1 hits never reach L2, so an
+ * Ident in constant use from L1 can still be evicted from an evicting L2.  A
+ * later L1 miss in another CachedFoundry then gets a new Ident for the same
+ * bytes, while the old Ident remains in any L1 slots holding it.  L1 slots
+ * are not cleared by RevolvingFoundry.OnEvict, as those callbacks run on
+ * whichever goroutine caused the rotation, and L1 is not threadsafe.
+ *
+ * L1 is a direct-mapped cache: each identifier has exactly one slot, indexed by
+ * its HashL, and a newer identifier simply replaces an older one in the same
+ * slot.  The slots store both hash halves inline, so a lookup costs a hash of
+ * the input and a single slot comparison.
+ */
+
+// A CachedFoundry caches identifiers from a shared backing foundry in a
+// small, fixed-size cache.  A CachedFoundry is not threadsafe and must be
+// used by only one goroutine; the backing foundry must be threadsafe if it is
+// shared.
+//
+// The hit and miss counters for both levels are available as fields.  L2Hits
+// are only counted if the backing foundry is a HitFoundry; otherwise every L1
+// miss is counted as an L2 miss.
+//
+// With an evicting backing foundry, such as a RevolvingFoundry, an Ident held
+// in L1 may outlive its eviction from the backing foundry, after which the
+// backing foundry returns a new Ident for the same bytes.  Callers relying on
+// pointer identity across CachedFoundries should size the backing foundry so
+// that identifiers in use are not evicted.
+type CachedFoundry struct {
+	backing Foundry
+	hit     HitFoundry
+	lookup  LookupFoundry
+	slots   []identSlot
+	mask    uint64
+
+	// Count of lookups that hit or missed in this cache (L1), and in the
+	// backing foundry (L2).
+	L1Hits, L1Misses, L2Hits, L2Misses uint64
+}
+
+// Create a CachedFoundry in front of the given backing foundry, with the
+// number of cache slots given by WithCacheSize (default 1024).
+func NewCachedFoundry(backing Foundry, opts ...Option) (*CachedFoundry, error) {
+	o, err := applyOptions(opts, optCacheSize)
+	if err != nil {
+		return nil, err
+	}
+	if backing == nil {
+		return nil, errors.New("backing foundry must not be nil")
+	}
+
+	slots := 1
+	for slots < o.cacheSize {
+		slots *= 2
+	}
+
+	hit, _ := backing.(HitFoundry)
+	lookup, _ := backing.(LookupFoundry)
+
+	return &CachedFoundry{
+		backing: backing,
+		hit:     hit,
+		lookup:  lookup,
+		slots:   make([]identSlot, slots),
+		mask:    uint64(slots - 1),
+	}, nil
+}
+
+func (f *CachedFoundry) Ident(ident []byte) Ident {
+	hashH, hashL := hashIdent(ident)
+	slot := &f.slots[hashL&f.mask]
+	if slot.ident != nil && slot.hashL == hashL && slot.hashH == hashH {
+		f.L1Hits++
+		return slot.ident
+	}
+	f.L1Misses++
+
+	var rv Ident
+	var hit bool
+	if f.hit != nil {
+		rv, hit = f.hit.IdentHit(ident)
+	} else {
+		rv = f.backing.Ident(ident)
+	}
+	if hit {
+		f.L2Hits++
+	} else {
+		f.L2Misses++
+	}
+
+	*slot = identSlot{hashH: hashH, hashL: hashL, ident: rv}
+	return rv
+}
+
+// Get returns the Ident with the given hash, consulting the backing foundry
+// if it is a LookupFoundry.  This does not update the hit and miss counters.
+func (f *CachedFoundry) Get(hashH, hashL uint64) Ident {
+	slot := &f.slots[hashL&f.mask]
+	if slot.ident != nil && slot.hashL == hashL && slot.hashH == hashH {
+		return slot.ident
+	}
+	if f.lookup != nil {
+		return f.lookup.Get(hashH, hashL)
+	}
+	return nil
+}
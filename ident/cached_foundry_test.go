@@ -0,0 +1,159 @@
+package ident
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// identOnlyFoundry hides any methods of the inner foundry other than Ident
+type identOnlyFoundry struct {
+	inner Foundry
+}
+
+func (f identOnlyFoundry) Ident(ident []byte) Ident {
+	return f.inner.Ident(ident)
+}
+
+func TestCachedFoundry(t *testing.T) {
+	backing, err := NewInternFoundry()
+	require.NoError(t, err)
+	f, err := NewCachedFoundry(backing, WithCacheSize(16))
+	require.NoError(t, err)
+
+	id1 := f.Ident([]byte("abc"))
+	require.Equal(t, uint64(0), f.L1Hits)
+	require.Equal(t, uint64(1), f.L1Misses)
+	require.Equal(t, uint64(0), f.L2Hits)
+	require.Equal(t, uint64(1), f.L2Misses)
+
+	id2 := f.Ident([]byte("abc"))
+	require.True(t, &id1[0] == &id2[0])
+	require.Equal(t, uint64(1), f.L1Hits)
+	require.Equal(t, uint64(1), f.L1Misses)
+
+	// an ident interned in the backing foundry, but not yet in L1
+	id3 := backing.Ident([]byte("def"))
+	id4 := f.Ident([]byte("def"))
+	require.True(t, &id3[0] == &id4[0])
+	require.Equal(t, uint64(2), f.L1Misses)
+	require.Equal(t, uint64(1), f.L2Hits)
+	require.Equal(t, uint64(1), f.L2Misses)
+
+	require.True(t, &id1[0] == &f.Get(id1.Hash())[0])
+	require.Nil(t, f.Get(0x123, 0x456))
+}
+
+// countingFoundry counts the calls to an inner InternFoundry
+type countingFoundry struct {
+	inner *InternFoundry
+	calls int
+}
+
+func (f *countingFoundry) Ident(ident []byte) Ident {
+	f.calls++
+	return f.inner.Ident(ident)
+}
+
+func (f *countingFoundry) IdentHit(ident []byte) (Ident, bool) {
+	f.calls++
+	return f.inner.IdentHit(ident)
+}
+
+func TestCachedFoundryOneBackingCall(t *testing.T) {
+	backing := &countingFoundry{inner: Must(NewInternFoundry()).(*InternFoundry)}
+	f, err := NewCachedFoundry(backing, WithCacheSize(1))
+	require.NoError(t, err)
+
+	// each L1 miss calls the backing foundry exactly once, hit or miss
+	for _, id := range []string{"a", "b", "a", "a"} {
+		f.Ident([]byte(id))
+	}
+	require.Equal(t, 3, backing.calls)
+	require.Equal(t, uint64(1), f.L1Hits)
+	require.Equal(t, uint64(1), f.L2Hits)
+	require.Equal(t, uint64(2), f.L2Misses)
+}
+
+func TestCachedFoundryEviction(t *testing.T) {
+	backing, err := NewInternFoundry()
+	require.NoError(t, err)
+	// a single slot, so every new ident evicts the last
+	f, err := NewCachedFoundry(backing, WithCacheSize(1))
+	require.NoError(t, err)
+
+	a1 := f.Ident([]byte("a"))
+	f.Ident([]byte("b"))
+	a2 := f.Ident([]byte("a"))
+
+	// still pointer-identical, via the backing foundry
+	require.True(t, &a1[0] == &a2[0])
+	require.Equal(t, uint64(0), f.L1Hits)
+	require.Equal(t, uint64(3), f.L1Misses)
+	require.Equal(t, uint64(1), f.L2Hits)
+	require.Equal(t, uint64(2), f.L2Misses)
+}
+
+func TestCachedFoundryNoHit(t *testing.T) {
+	f, err := NewCachedFoundry(identOnlyFoundry{Must(NewInternFoundry())}, WithCacheSize(16))
+	require.NoError(t, err)
+
+	f.Ident([]byte("abc"))
+	f.Ident([]byte("abc"))
+	require.Equal(t, uint64(1), f.L1Hits)
+	require.Equal(t, uint64(1), f.L1Misses)
+	require.Equal(t, uint64(0), f.L2Hits)
+	require.Equal(t, uint64(1), f.L2Misses)
+}
+
+func TestCachedFoundryShared(t *testing.T) {
+	backing, err := NewThreadsafeFoundry(Must(NewInternFoundry()))
+	require.NoError(t, err)
+
+	const workers = 4
+	results := make([][]Ident, workers)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		f, err := NewCachedFoundry(backing, WithCacheSize(64))
+		require.NoError(t, err)
+		go func(w int) {
+			for i := 0; i < 100; i++ {
+				results[w] = append(results[w], f.Ident([]byte(fmt.Sprintf("tag:%d", i))))
+			}
+			wg.Done()
+		}(w)
+	}
+	wg.Wait()
+
+	for w := 1; w < workers; w++ {
+		for i := range results[w] {
+			require.True(t, &results[0][i][0] == &results[w][i][0])
+		}
+	}
+}
+
+func TestCachedFoundryRevolvingBacking(t *testing.T) {
+	revolving, err := NewRevolvingFoundry(WithGenerations(2), WithRotateAfter(2))
+	require.NoError(t, err)
+	backing, err := NewThreadsafeFoundry(revolving)
+	require.NoError(t, err)
+
+	first, err := NewCachedFoundry(backing)
+	require.NoError(t, err)
+	a1 := first.Ident([]byte("a"))
+
+	for i := 0; i < 20; i++ {
+		// an L2 hit from a fresh L1 counts as an access in the backing
+		// foundry, so `a` survives the rotations caused by other idents
+		f, err := NewCachedFoundry(backing)
+		require.NoError(t, err)
+		a2 := f.Ident([]byte("a"))
+		require.Equal(t, uint64(1), f.L2Hits)
+		require.True(t, &a1[0] == &a2[0], "iteration %d", i)
+
+		backing.Ident([]byte(fmt.Sprintf("x:%d", i)))
+	}
+}
@@ -0,0 +1,73 @@
+package ident_test
+
+import (
+	"testing"
+
+	"github.com/djmitche/tagset/ident"
+	"github.com/djmitche/tagset/ident/identtest"
+	"github.com/stretchr/testify/suite"
+)
+
+// Run the conformance suite against each of the foundries in this package
+
+func TestNullFoundryConformance(t *testing.T) {
+	suite.Run(t, &identtest.FoundrySuite{
+		NewFoundry: func() ident.Foundry { return ident.Must(ident.NewNullFoundry()) },
+		Threadsafe: true,
+	})
+}
+
+func TestInternFoundryConformance(t *testing.T) {
+	suite.Run(t, &identtest.FoundrySuite{
+		NewFoundry: func() ident.Foundry { return ident.Must(ident.NewInternFoundry()) },
+		Interns:    true,
+	})
+}
+
+func TestRevolvingFoundryConformance(t *testing.T) {
+	suite.Run(t, &identtest.FoundrySuite{
+		NewFoundry: func() ident.Foundry { return ident.Must(ident.NewRevolvingFoundry(ident.WithRotateAfter(100))) },
+		Interns:    true,
+	})
+}
+
+func TestThreadsafeFoundryConformance(t *testing.T) {
+	suite.Run(t, &identtest.FoundrySuite{
+		NewFoundry: func() ident.Foundry {
+			return ident.Must(ident.NewThreadsafeFoundry(ident.Must(ident.NewInternFoundry())))
+		},
+		Interns:    true,
+		Threadsafe: true,
+	})
+}
+
+func TestCachedFoundryConformance(t *testing.T) {
+	suite.Run(t, &identtest.FoundrySuite{
+		NewFoundry: func() ident.Foundry {
+			backing := ident.Must(ident.NewThreadsafeFoundry(ident.Must(ident.NewInternFoundry())))
+			return ident.Must(ident.NewCachedFoundry(backing, ident.WithCacheSize(64)))
+		},
+		Interns: true,
+	})
+}
+
+func TestFrozenFoundryConformance(t *testing.T) {
+	frozen := [][]byte{[]byte("x:abc"), []byte("tag:1")}
+	suite.Run(t, &identtest.FoundrySuite{
+		NewFoundry: func() ident.Foundry { return ident.Must(ident.NewFrozenFoundry(frozen, nil)) },
+		Threadsafe: true,
+	})
+	suite.Run(t, &identtest.FoundrySuite{
+		NewFoundry: func() ident.Foundry {
+			return ident.Must(ident.NewFrozenFoundry(frozen, ident.Must(ident.NewInternFoundry())))
+		},
+		Interns: true,
+	})
+}
+
+func TestAdaptiveFoundryConformance(t *testing.T) {
+	suite.Run(t, &identtest.FoundrySuite{
+		NewFoundry: func() ident.Foundry { return ident.Must(ident.NewAdaptiveFoundry(ident.WithWindow(100))) },
+		Interns:    true,
+	})
+}
@@ -7,3 +7,23 @@ type Foundry interface {
 	// not maintained, and the caller may reuse it.
 	Ident([]byte) Ident
 }
+
+// A LookupFoundry is a Foundry which can also look up identifiers it has
+// already produced, by hash, without creating new identifiers.
+type LookupFoundry interface {
+	Foundry
+
+	// Get returns the Ident with the given hash, or nil if the foundry does
+	// not have one.
+	Get(hashH, hashL uint64) Ident
+}
+
+// A HitFoundry is a Foundry which can also report whether it already had an
+// identifier, in the same operation that produces it.
+type HitFoundry interface {
+	Foundry
+
+	// IdentHit is like Ident, and also returns true if the foundry already
+	// had the identifier.
+	IdentHit([]byte) (Ident, bool)
+}
@@ -0,0 +1,97 @@
+package ident
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// A FrozenFoundry contains a fixed set of identifiers, given when it is
+// created, such as host tags or configured global tags.  Since it never
+// changes after creation, it is safe for concurrent use without locking.
+//
+// Identifiers not in the fixed set are passed to the `next` foundry, making a
+// FrozenFoundry suitable as a first-lookup layer in front of any other
+// foundry.  If `next` is nil, such identifiers are created fresh, as by a
+// NullFoundry.  A FrozenFoundry is only as threadsafe as its `next` foundry.
+type FrozenFoundry struct {
+	byHash *identTable
+	next   Foundry
+}
+
+// Create a FrozenFoundry containing the given identifiers.  The byte slices
+// are not maintained, and the caller may reuse them.
+func NewFrozenFoundry(idents [][]byte, next Foundry) (*FrozenFoundry, error) {
+	byHash := newIdentTable(len(idents))
+	for _, ident := range idents {
+		hashH, hashL := hashIdent(ident)
+		if byHash.get(hashH, hashL) == nil {
+			byHash.insert(hashH, hashL, newIdent(ident, hashH, hashL))
+		}
+	}
+
+	return &FrozenFoundry{
+		byHash: byHash,
+		next:   next,
+	}, nil
+}
+
+// Create a FrozenFoundry containing the identifiers read from the given
+// reader, one per line.  Leading and trailing whitespace is ignored, as are
+// empty lines and lines beginning with `#`.
+func LoadFrozenFoundry(r io.Reader, next Foundry) (*FrozenFoundry, error) {
+	idents, err := readIdents(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFrozenFoundry(idents, next)
+}
+
+// readIdents reads identifiers from a reader in the format described for
+// LoadFrozenFoundry
+func readIdents(r io.Reader) ([][]byte, error) {
+	var idents [][]byte
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		idents = append(idents, append([]byte{}, line...))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return idents, nil
+}
+
+func (f *FrozenFoundry) Ident(ident []byte) Ident {
+	hashH, hashL := hashIdent(ident)
+	if hit := f.byHash.get(hashH, hashL); hit != nil {
+		return hit
+	}
+
+	if f.next != nil {
+		return f.next.Ident(ident)
+	}
+	return newIdent(ident, hashH, hashL)
+}
+
+// Get returns the Ident with the given hash from the fixed set, or from the
+// `next` foundry if it is a LookupFoundry, or nil.
+func (f *FrozenFoundry) Get(hashH, hashL uint64) Ident {
+	if hit := f.byHash.get(hashH, hashL); hit != nil {
+		return hit
+	}
+
+	if lookup, ok := f.next.(LookupFoundry); ok {
+		return lookup.Get(hashH, hashL)
+	}
+	return nil
+}
+
+// Len returns the number of identifiers in the fixed set.
+func (f *FrozenFoundry) Len() int {
+	return f.byHash.len()
+}
@@ -0,0 +1,89 @@
+package ident
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrozenFoundry(t *testing.T) {
+	f, err := NewFrozenFoundry([][]byte{
+		[]byte("host:foo"),
+		[]byte("env:prod"),
+		[]byte("env:prod"),
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, f.Len())
+
+	id1 := f.Ident([]byte("env:prod"))
+	id2 := f.Ident([]byte("env:prod"))
+	require.True(t, &id1[0] == &id2[0])
+	require.True(t, &id1[0] == &f.Get(id1.Hash())[0])
+
+	// not frozen, so not deduplicated
+	id3 := f.Ident([]byte("env:dev"))
+	id4 := f.Ident([]byte("env:dev"))
+	require.True(t, &id3[0] != &id4[0])
+	require.True(t, id3.Equals(id4))
+	require.Nil(t, f.Get(id3.Hash()))
+}
+
+func TestFrozenFoundryNext(t *testing.T) {
+	next, err := NewInternFoundry()
+	require.NoError(t, err)
+	f, err := NewFrozenFoundry([][]byte{[]byte("host:foo")}, next)
+	require.NoError(t, err)
+
+	id1 := f.Ident([]byte("env:dev"))
+	id2 := f.Ident([]byte("env:dev"))
+	require.True(t, &id1[0] == &id2[0])
+	require.True(t, &id1[0] == &f.Get(id1.Hash())[0])
+
+	// frozen idents are not passed to next
+	host := f.Ident([]byte("host:foo"))
+	require.Nil(t, next.Get(host.Hash()))
+}
+
+func TestLoadFrozenFoundry(t *testing.T) {
+	config := `
+# host tags
+host:foo
+  env:prod
+
+team:bar
+`
+	f, err := LoadFrozenFoundry(strings.NewReader(config), nil)
+	require.NoError(t, err)
+	require.Equal(t, 3, f.Len())
+
+	for _, tag := range []string{"host:foo", "env:prod", "team:bar"} {
+		id := makeIdent(tag)
+		got := f.Get(id.Hash())
+		require.NotNil(t, got, tag)
+		require.Equal(t, []byte(tag), got.Bytes())
+	}
+}
+
+func TestFrozenFoundryConcurrent(t *testing.T) {
+	f, err := NewFrozenFoundry([][]byte{[]byte("a"), []byte("b")}, nil)
+	require.NoError(t, err)
+	a := f.Ident([]byte("a"))
+
+	wg := sync.WaitGroup{}
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			for i := 0; i < 1000; i++ {
+				got := f.Ident([]byte("a"))
+				if &got[0] != &a[0] {
+					panic("not interned")
+				}
+				f.Ident([]byte("c"))
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,210 @@
+// The `identtest` package provides a conformance test suite for implementations
+// of `ident.Foundry`.  To validate a foundry, run the suite with a function
+// that creates new instances of it:
+//
+//	func TestMyFoundry(t *testing.T) {
+//		suite.Run(t, &identtest.FoundrySuite{
+//			NewFoundry: func() ident.Foundry { return NewMyFoundry() },
+//			Interns:    true,
+//		})
+//	}
+package identtest
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/djmitche/tagset/ident"
+	"github.com/stretchr/testify/suite"
+	"github.com/twmb/murmur3"
+)
+
+// A FoundrySuite puts a foundry through its paces for the behavior defined by
+// the `ident.Foundry` interface, and for the optional guarantees described by
+// its fields.  Foundry-specific tests should occur in a separate suite, which
+// may embed this one.
+type FoundrySuite struct {
+	suite.Suite
+
+	// NewFoundry creates a new, empty foundry.  It is called before each
+	// test.
+	NewFoundry func() ident.Foundry
+
+	// Interns is true if the foundry returns pointer-identical Idents for
+	// identical bytes, at least when those bytes were seen recently.
+	Interns bool
+
+	// Threadsafe is true if the foundry may be used concurrently from
+	// multiple goroutines.
+	Threadsafe bool
+
+	f ident.Foundry
+}
+
+func (s *FoundrySuite) SetupTest() {
+	s.f = s.NewFoundry()
+}
+
+// testIdents is a collection of interesting identifiers
+var testIdents = [][]byte{
+	{},
+	[]byte("a"),
+	[]byte("x:abc"),
+	[]byte("host:i-1234567890abcdef0"),
+	[]byte("with spaces and unicode: é世"),
+	{0, 1, 2, 0xff, 0xfe},
+	bytes.Repeat([]byte("long"), 1000),
+}
+
+func (s *FoundrySuite) TestHash() {
+	for _, b := range testIdents {
+		id := s.f.Ident(b)
+		expH, expL := murmur3.Sum128(b)
+		gotH, gotL := id.Hash()
+		s.Equal(expH, gotH)
+		s.Equal(expL, gotL)
+		s.Equal(expH, id.HashH())
+		s.Equal(expL, id.HashL())
+	}
+}
+
+func (s *FoundrySuite) TestBytes() {
+	for _, b := range testIdents {
+		id := s.f.Ident(b)
+		s.Equal(b, id.Bytes())
+	}
+}
+
+func (s *FoundrySuite) TestEquals() {
+	id1 := s.f.Ident([]byte("x:abc"))
+	id2 := s.f.Ident([]byte("x:abc"))
+	id3 := s.f.Ident([]byte("x:def"))
+
+	s.True(id1.Equals(id2))
+	s.True(id2.Equals(id1))
+	s.False(id1.Equals(id3))
+	s.False(id3.Equals(id1))
+}
+
+// The caller may reuse the buffer passed to `Ident`, without affecting the
+// returned Ident.
+func (s *FoundrySuite) TestBufferReuse() {
+	buf := []byte("x:abc")
+	id1 := s.f.Ident(buf)
+
+	copy(buf, "y:def")
+	s.Equal([]byte("x:abc"), id1.Bytes())
+
+	id2 := s.f.Ident(buf)
+	s.Equal([]byte("y:def"), id2.Bytes())
+	s.Equal([]byte("x:abc"), id1.Bytes())
+	s.False(id1.Equals(id2))
+}
+
+// Many distinct identifiers each get the correct bytes and hashes.
+func (s *FoundrySuite) TestManyIdents() {
+	idents := make([]ident.Ident, 10000)
+	for i := range idents {
+		idents[i] = s.f.Ident([]byte(fmt.Sprintf("tag:%d", i)))
+	}
+
+	for i, id := range idents {
+		b := []byte(fmt.Sprintf("tag:%d", i))
+		s.Equal(b, id.Bytes())
+		expH, expL := murmur3.Sum128(b)
+		s.Equal(expH, id.HashH())
+		s.Equal(expL, id.HashL())
+	}
+}
+
+func (s *FoundrySuite) TestInterning() {
+	if !s.Interns {
+		s.T().Skip("foundry does not intern")
+	}
+
+	id1 := s.f.Ident([]byte("x:abc"))
+	id2 := s.f.Ident([]byte("x:abc"))
+	s.True(&id1[0] == &id2[0], "repeated identifiers are pointer-identical")
+
+	// a small set of identifiers, seen repeatedly, remains interned
+	first := make([]ident.Ident, 10)
+	for round := 0; round < 3; round++ {
+		for i := range first {
+			id := s.f.Ident([]byte(fmt.Sprintf("tag:%d", i)))
+			if round == 0 {
+				first[i] = id
+			} else {
+				s.True(&first[i][0] == &id[0], "round %d, tag %d", round, i)
+			}
+		}
+	}
+}
+
+func (s *FoundrySuite) TestLookup() {
+	lookup, ok := s.f.(ident.LookupFoundry)
+	if !ok {
+		s.T().Skip("foundry is not a LookupFoundry")
+	}
+
+	s.Nil(lookup.Get(murmur3.Sum128([]byte("never-seen"))))
+
+	id := s.f.Ident([]byte("x:abc"))
+	got := lookup.Get(id.Hash())
+	if s.Interns {
+		s.NotNil(got)
+	}
+	if got != nil {
+		s.True(got.Equals(id))
+		s.Equal(id.Bytes(), got.Bytes())
+	}
+}
+
+func (s *FoundrySuite) TestIdentHit() {
+	hitFoundry, ok := s.f.(ident.HitFoundry)
+	if !ok {
+		s.T().Skip("foundry is not a HitFoundry")
+	}
+
+	id1, hit := hitFoundry.IdentHit([]byte("x:abc"))
+	s.False(hit)
+	id2, hit := hitFoundry.IdentHit([]byte("x:abc"))
+	s.True(id1.Equals(id2))
+	if s.Interns {
+		s.True(hit)
+		s.True(&id1[0] == &id2[0])
+	}
+}
+
+func (s *FoundrySuite) TestConcurrent() {
+	if !s.Threadsafe {
+		s.T().Skip("foundry is not threadsafe")
+	}
+
+	const workers = 8
+	const count = 1000
+	results := make([][]ident.Ident, workers)
+
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func(w int) {
+			defer wg.Done()
+			results[w] = make([]ident.Ident, count)
+			for i := 0; i < count; i++ {
+				results[w][i] = s.f.Ident([]byte(fmt.Sprintf("tag:%d", i%100)))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for w := 0; w < workers; w++ {
+		for i := 0; i < count; i++ {
+			id := results[w][i]
+			s.Equal([]byte(fmt.Sprintf("tag:%d", i%100)), id.Bytes())
+			if s.Interns {
+				s.True(&results[0][i][0] == &id[0])
+			}
+		}
+	}
+}
@@ -2,58 +2,65 @@ package ident
 
 /* IMPLEMENTATION NOTES
  *
- * This foundry uses [2-choice hashing](https://en.wikipedia.org/wiki/2-choice_hashing) with
- * eviction on collision.  It uses the high- and low-order 64 bits of the hash as the two
- * distinct hashes.  It compares all 128 bits to determine a hit.  On a collision of either
- * the high or low bits during insertion, it overwrites the existing Ident.  This means that
- * in the case of a collision, some interned data may be duplicated, failing safe.
+ * This foundry stores identifiers in an `identTable`, an open-addressing hash
+ * table keyed by the full 128-bit hash.  Two identifiers collide only if all
+ * 128 bits of their hashes are equal, in which case the later identifier
+ * replaces the earlier one; this is considered unlikely enough to ignore.
  *
  * Note that Murmur3 is _not_ cryptographically collision-resistant, so a
  * particularly abusive user of this foundry could, with moderate effort, cause
- * significant duplicated data.  For purposes of agent performance, this is not
+ * identifiers to be confused.  For purposes of agent performance, this is not
  * an issue as users generally want the agent to perform well.
  */
 
 // A InternFoundry caches identifiers forever, effectively acting like a
 // string interner.
 type InternFoundry struct {
-	byHash map[uint64]Ident
+	byHash *identTable
 }
 
-func NewInternFoundry() *InternFoundry {
-	return &InternFoundry{byHash: map[uint64]Ident{}}
+// Create an InternFoundry.  The WithCapacity option pre-sizes its table.
+func NewInternFoundry(opts ...Option) (*InternFoundry, error) {
+	o, err := applyOptions(opts, optCapacity)
+	if err != nil {
+		return nil, err
+	}
+	return newInternFoundry(o.capacity), nil
+}
+
+func newInternFoundry(capacity int) *InternFoundry {
+	return &InternFoundry{byHash: newIdentTable(capacity)}
 }
 
 func (f *InternFoundry) Ident(ident []byte) Ident {
+	rv, _ := f.IdentHit(ident)
+	return rv
+}
+
+// IdentHit returns the Ident for the given byte slice, and whether it was
+// already interned.
+func (f *InternFoundry) IdentHit(ident []byte) (Ident, bool) {
 	hashH, hashL := hashIdent(ident)
 	existing := f.get(hashH, hashL)
 	if existing != nil {
-		return existing
+		return existing, true
 	}
 
 	rv := newIdent(ident, hashH, hashL)
 	f.insert(hashH, hashL, rv)
 
-	return rv
+	return rv, false
 }
 
-func (f *InternFoundry) get(hashH, hashL uint64) Ident {
-	var hit Ident
-
-	hit = f.byHash[hashH]
-	if hit != nil && hit.HashL() == hashL && hit.HashH() == hashH {
-		return hit
-	}
-
-	hit = f.byHash[hashL]
-	if hit != nil && hit.HashL() == hashL && hit.HashH() == hashH {
-		return hit
-	}
+// Get returns the interned Ident with the given hash, or nil if there is none.
+func (f *InternFoundry) Get(hashH, hashL uint64) Ident {
+	return f.get(hashH, hashL)
+}
 
-	return nil
+func (f *InternFoundry) get(hashH, hashL uint64) Ident {
+	return f.byHash.get(hashH, hashL)
 }
 
 func (f *InternFoundry) insert(hashH, hashL uint64, ident Ident) {
-	f.byHash[hashH] = ident
-	f.byHash[hashL] = ident
+	f.byHash.insert(hashH, hashL, ident)
 }
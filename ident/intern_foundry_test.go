@@ -7,7 +7,8 @@ import (
 )
 
 func TestInternFoundry(t *testing.T) {
-	f := NewInternFoundry()
+	f, err := NewInternFoundry()
+	require.NoError(t, err)
 
 	id1 := f.Ident([]byte("aaa"))
 	id2 := f.Ident([]byte("aaa"))
@@ -36,3 +37,13 @@ func TestInternFoundry(t *testing.T) {
 	id5 := f.get(0x123, 0x456)
 	require.Nil(t, id5)
 }
+
+func TestInternFoundryGet(t *testing.T) {
+	f, err := NewInternFoundry()
+	require.NoError(t, err)
+
+	id1 := f.Ident([]byte("aaa"))
+	id2 := f.Get(id1.Hash())
+	require.True(t, &id1[0] == &id2[0])
+	require.Nil(t, f.Get(0x123, 0x456))
+}
@@ -5,8 +5,8 @@ package ident
 // will only be seen once)
 type NullFoundry struct{}
 
-func NewNullFoundry() *NullFoundry {
-	return &NullFoundry{}
+func NewNullFoundry() (*NullFoundry, error) {
+	return &NullFoundry{}, nil
 }
 
 func (f *NullFoundry) Ident(ident []byte) Ident {
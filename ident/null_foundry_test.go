@@ -7,7 +7,8 @@ import (
 )
 
 func TestNullFoundryIdent(t *testing.T) {
-	f := NewNullFoundry()
+	f, err := NewNullFoundry()
+	require.NoError(t, err)
 
 	id1 := f.Ident([]byte("abc:def"))
 	id2 := f.Ident([]byte("abc:def"))
@@ -22,6 +23,7 @@ func TestNullFoundryIdent(t *testing.T) {
 }
 
 func TestNullFoundryGet(t *testing.T) {
-	f := NewNullFoundry()
+	f, err := NewNullFoundry()
+	require.NoError(t, err)
 	require.Nil(t, f.Get(0x123, 0x456))
 }
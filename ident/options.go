@@ -0,0 +1,186 @@
+package ident
+
+import "fmt"
+
+// An Option configures a foundry when it is created, and is passed to the
+// foundry's constructor.  Each constructor that accepts Options documents
+// those it uses, and returns an error for options that do not apply to it.
+type Option func(*options) error
+
+// An optionID identifies an Option, so that constructors can reject options
+// that do not apply to them.
+type optionID int
+
+const (
+	optCapacity optionID = iota
+	optGenerations
+	optRotateAfter
+	optWindow
+	optMaxEntries
+	optCacheSize
+)
+
+// String returns the name of the function creating the option
+func (id optionID) String() string {
+	switch id {
+	case optCapacity:
+		return "WithCapacity"
+	case optGenerations:
+		return "WithGenerations"
+	case optRotateAfter:
+		return "WithRotateAfter"
+	case optWindow:
+		return "WithWindow"
+	case optMaxEntries:
+		return "WithMaxEntries"
+	case optCacheSize:
+		return "WithCacheSize"
+	default:
+		return fmt.Sprintf("optionID(%d)", int(id))
+	}
+}
+
+// options collects the configuration from a list of Options
+type options struct {
+	// capacity is the number of identifiers for which to pre-size tables
+	capacity int
+
+	// generations and rotateAfter configure RevolvingFoundry
+	generations int
+	rotateAfter int
+
+	// window and maxEntries configure AdaptiveFoundry
+	window     int
+	maxEntries int
+
+	// cacheSize configures CachedFoundry
+	cacheSize int
+
+	// given contains the options that were given
+	given []optionID
+}
+
+// defaultOptions are used for any options not given to a constructor
+var defaultOptions = options{
+	capacity:    0,
+	generations: 3,
+	rotateAfter: 5000,
+	window:      10000,
+	maxEntries:  1000000,
+	cacheSize:   1024,
+}
+
+// applyOptions applies the given options to the defaults, returning the first
+// error encountered.  Options not in `accepted` are rejected.
+func applyOptions(opts []Option, accepted ...optionID) (options, error) {
+	o := defaultOptions
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return o, err
+		}
+	}
+Given:
+	for _, id := range o.given {
+		for _, a := range accepted {
+			if id == a {
+				continue Given
+			}
+		}
+		return o, fmt.Errorf("option %s does not apply to this foundry", id)
+	}
+	return o, nil
+}
+
+// WithCapacity pre-sizes the foundry's tables to hold the given number of
+// identifiers without rehashing.  For RevolvingFoundry, this applies to each
+// generation.
+func WithCapacity(capacity int) Option {
+	return func(o *options) error {
+		if capacity < 0 {
+			return fmt.Errorf("capacity must not be negative, got %d", capacity)
+		}
+		o.capacity = capacity
+		o.given = append(o.given, optCapacity)
+		return nil
+	}
+}
+
+// WithGenerations sets the number of generations in a RevolvingFoundry.  This
+// must be at least 2, and should typically be small, as it impacts the time
+// it takes to create a novel Ident.
+func WithGenerations(generations int) Option {
+	return func(o *options) error {
+		if generations < 2 {
+			return fmt.Errorf("generations must be at least 2, got %d", generations)
+		}
+		o.generations = generations
+		o.given = append(o.given, optGenerations)
+		return nil
+	}
+}
+
+// WithRotateAfter sets the number of accesses after which a RevolvingFoundry
+// rotates its generations.  A value of 0 disables rotation.  The default is
+// 5000.
+func WithRotateAfter(rotateAfter int) Option {
+	return func(o *options) error {
+		if rotateAfter < 0 {
+			return fmt.Errorf("rotateAfter must not be negative, got %d", rotateAfter)
+		}
+		o.rotateAfter = rotateAfter
+		o.given = append(o.given, optRotateAfter)
+		return nil
+	}
+}
+
+// WithWindow sets the number of accesses over which an AdaptiveFoundry
+// samples its hit rate.
+func WithWindow(window int) Option {
+	return func(o *options) error {
+		if window < 1 {
+			return fmt.Errorf("window must be at least 1, got %d", window)
+		}
+		o.window = window
+		o.given = append(o.given, optWindow)
+		return nil
+	}
+}
+
+// WithMaxEntries sets the approximate maximum number of identifiers an
+// AdaptiveFoundry will hold.  A value of 0 disables the limit.  The default
+// is 1,000,000.
+func WithMaxEntries(maxEntries int) Option {
+	return func(o *options) error {
+		if maxEntries < 0 {
+			return fmt.Errorf("maxEntries must not be negative, got %d", maxEntries)
+		}
+		o.maxEntries = maxEntries
+		o.given = append(o.given, optMaxEntries)
+		return nil
+	}
+}
+
+// WithCacheSize sets the number of slots in a CachedFoundry.  It is rounded
+// up to a power of two.
+func WithCacheSize(cacheSize int) Option {
+	return func(o *options) error {
+		if cacheSize < 1 {
+			return fmt.Errorf("cacheSize must be at least 1, got %d", cacheSize)
+		}
+		o.cacheSize = cacheSize
+		o.given = append(o.given, optCacheSize)
+		return nil
+	}
+}
+
+// Must is a helper that wraps a call to a foundry constructor, and panics if
+// the error is non-nil.  It is intended for use in variable initializations
+// such as
+//
+//	var idFoundry = ident.Must(ident.NewInternFoundry())
+func Must(f Foundry, err error) Foundry {
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
@@ -0,0 +1,97 @@
+package ident
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionValidation(t *testing.T) {
+	_, err := NewInternFoundry(WithCapacity(-1))
+	require.Error(t, err)
+
+	_, err = NewRevolvingFoundry(WithGenerations(1))
+	require.Error(t, err)
+
+	_, err = NewRevolvingFoundry(WithRotateAfter(-1))
+	require.Error(t, err)
+
+	_, err = NewAdaptiveFoundry(WithWindow(0))
+	require.Error(t, err)
+
+	_, err = NewAdaptiveFoundry(WithMaxEntries(-1))
+	require.Error(t, err)
+
+	_, err = NewCachedFoundry(Must(NewNullFoundry()), WithCacheSize(0))
+	require.Error(t, err)
+
+	_, err = NewCachedFoundry(nil)
+	require.Error(t, err)
+
+	_, err = NewThreadsafeFoundry(nil)
+	require.Error(t, err)
+}
+
+func TestOptionsNotApplicable(t *testing.T) {
+	_, err := NewInternFoundry(WithCacheSize(5))
+	require.EqualError(t, err, "option WithCacheSize does not apply to this foundry")
+
+	_, err = NewRevolvingFoundry(WithWindow(10))
+	require.Error(t, err)
+
+	_, err = NewAdaptiveFoundry(WithGenerations(4))
+	require.Error(t, err)
+
+	_, err = NewCachedFoundry(Must(NewNullFoundry()), WithCapacity(10))
+	require.Error(t, err)
+
+	_, err = NewArenaFoundry(WithRotateAfter(10))
+	require.Error(t, err)
+}
+
+func TestOptionsZeroDisables(t *testing.T) {
+	// with rotation disabled, identifiers are never dropped
+	f, err := NewRevolvingFoundry(WithRotateAfter(0))
+	require.NoError(t, err)
+	a1 := f.Ident([]byte("a"))
+	for i := 0; i < 10000; i++ {
+		f.Ident([]byte(fmt.Sprintf("tag:%d", i)))
+	}
+	require.True(t, &a1[0] == &f.Ident([]byte("a"))[0])
+
+	// with no entry limit, the Intern mode is kept however many identifiers
+	// it holds
+	af, err := NewAdaptiveFoundry(WithMaxEntries(0), WithWindow(100))
+	require.NoError(t, err)
+	for i := 0; i < 10000; i++ {
+		af.Ident([]byte(fmt.Sprintf("tag:%d", i%10)))
+		af.Ident([]byte(fmt.Sprintf("tag:%d", i)))
+	}
+	require.Equal(t, AdaptiveIntern, af.Mode())
+}
+
+func TestOptionDefaults(t *testing.T) {
+	f, err := NewRevolvingFoundry()
+	require.NoError(t, err)
+	require.Len(t, f.inner, 3)
+	require.Equal(t, 5000, f.rotateAfter)
+}
+
+func TestWithCapacity(t *testing.T) {
+	f, err := NewInternFoundry(WithCapacity(1000))
+	require.NoError(t, err)
+
+	slots := len(f.byHash.slots)
+	for i := 0; i < 1000; i++ {
+		f.Ident([]byte(fmt.Sprintf("tag:%d", i)))
+	}
+
+	// the table did not need to grow
+	require.Equal(t, slots, len(f.byHash.slots))
+}
+
+func TestMust(t *testing.T) {
+	require.NotNil(t, Must(NewInternFoundry()))
+	require.Panics(t, func() { Must(NewRevolvingFoundry(WithGenerations(1))) })
+}
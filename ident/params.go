@@ -0,0 +1,333 @@
+package ident
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Params are named parameters for constructing a foundry from configuration.
+// Values are typically those produced by a YAML or JSON decoder (strings,
+// numbers, lists, and nested maps), or strings parsed from a spec by
+// ParseSpec.
+type Params map[string]interface{}
+
+// Check returns an error if the params contain any key not in `allowed`.
+func (p Params) Check(allowed ...string) error {
+	var unknown []string
+Keys:
+	for k := range p {
+		for _, a := range allowed {
+			if k == a {
+				continue Keys
+			}
+		}
+		unknown = append(unknown, k)
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown parameters: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// Has returns true if the named parameter is present.
+func (p Params) Has(key string) bool {
+	_, found := p[key]
+	return found
+}
+
+// Int returns the named parameter as an int, or `def` if it is not present.
+func (p Params) Int(key string, def int) (int, error) {
+	v, found := p[key]
+	if !found {
+		return def, nil
+	}
+
+	switch v := v.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case uint64:
+		return int(v), nil
+	case float64:
+		if v != float64(int(v)) {
+			return 0, fmt.Errorf("parameter %s must be an integer, got %v", key, v)
+		}
+		return int(v), nil
+	case string:
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("parameter %s must be an integer, got %q", key, v)
+		}
+		return i, nil
+	default:
+		return 0, fmt.Errorf("parameter %s must be an integer, got %T", key, v)
+	}
+}
+
+// Bool returns the named parameter as a bool, or `def` if it is not present.
+// The strings `true` and `false` are also accepted.
+func (p Params) Bool(key string, def bool) (bool, error) {
+	v, found := p[key]
+	if !found {
+		return def, nil
+	}
+
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, fmt.Errorf("parameter %s must be a boolean, got %q", key, v)
+		}
+		return b, nil
+	default:
+		return false, fmt.Errorf("parameter %s must be a boolean, got %T", key, v)
+	}
+}
+
+// An IntParam names an optional int parameter, and the function to call with
+// its value.
+type IntParam struct {
+	Key   string
+	Apply func(int)
+}
+
+// ApplyInts calls Apply with the value of each of the given parameters that
+// is present, in order, and returns the first error.
+func (p Params) ApplyInts(ints ...IntParam) error {
+	for _, param := range ints {
+		if !p.Has(param.Key) {
+			continue
+		}
+		v, err := p.Int(param.Key, 0)
+		if err != nil {
+			return err
+		}
+		param.Apply(v)
+	}
+	return nil
+}
+
+// A BoolParam names an optional bool parameter, and the function to call
+// with its value.
+type BoolParam struct {
+	Key   string
+	Apply func(bool)
+}
+
+// ApplyBools calls Apply with the value of each of the given parameters that
+// is present, in order, and returns the first error.
+func (p Params) ApplyBools(bools ...BoolParam) error {
+	for _, param := range bools {
+		if !p.Has(param.Key) {
+			continue
+		}
+		v, err := p.Bool(param.Key, false)
+		if err != nil {
+			return err
+		}
+		param.Apply(v)
+	}
+	return nil
+}
+
+// Duration returns the named parameter as a time.Duration, or `def` if it is
+// not present.  Strings are parsed with time.ParseDuration, and must include
+// a unit, such as `10m`.  Bare numbers are rejected, as their unit would be
+// ambiguous.
+func (p Params) Duration(key string, def time.Duration) (time.Duration, error) {
+	v, found := p[key]
+	if !found {
+		return def, nil
+	}
+
+	switch v := v.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("parameter %s must be a duration with a unit, such as 10m, got %q", key, v)
+		}
+		return d, nil
+	default:
+		return 0, fmt.Errorf("parameter %s must be a duration with a unit, such as 10m, got %T", key, v)
+	}
+}
+
+// String returns the named parameter as a string, or `def` if it is not
+// present.
+func (p Params) String(key string, def string) (string, error) {
+	v, found := p[key]
+	if !found {
+		return def, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("parameter %s must be a string, got %T", key, v)
+	}
+	return s, nil
+}
+
+// Strings returns the named parameter as a list of strings, or nil if it is
+// not present.  A single string is split on commas.
+func (p Params) Strings(key string) ([]string, error) {
+	v, found := p[key]
+	if !found {
+		return nil, nil
+	}
+
+	switch v := v.(type) {
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		return strings.Split(v, ","), nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		rv := make([]string, len(v))
+		for i, elt := range v {
+			s, ok := elt.(string)
+			if !ok {
+				return nil, fmt.Errorf("parameter %s must be a list of strings, got %T element", key, elt)
+			}
+			rv[i] = s
+		}
+		return rv, nil
+	default:
+		return nil, fmt.Errorf("parameter %s must be a list of strings, got %T", key, v)
+	}
+}
+
+// Spec returns the named parameter as a foundry name and its parameters, for
+// foundries which wrap other foundries.  The parameter may be a spec string
+// (see ParseSpec), or a map with a `name` key and the remaining keys as
+// parameters.  If the parameter is not present, `def` is parsed as a spec.
+func (p Params) Spec(key string, def string) (string, Params, error) {
+	v, found := p[key]
+	if !found {
+		return ParseSpec(def)
+	}
+
+	var m map[string]interface{}
+	switch v := v.(type) {
+	case string:
+		return ParseSpec(v)
+	case Params:
+		m = v
+	case map[string]interface{}:
+		m = v
+	default:
+		return "", nil, fmt.Errorf("parameter %s must be a foundry spec, got %T", key, v)
+	}
+
+	name, ok := m["name"].(string)
+	if !ok {
+		return "", nil, fmt.Errorf("parameter %s must have a string `name`", key)
+	}
+	params := Params{}
+	for k, v := range m {
+		if k != "name" {
+			params[k] = v
+		}
+	}
+	return name, params, nil
+}
+
+// ParseSpec parses a compact foundry specification of the form
+// `name{key:value,key:value}`, where the braced parameters are optional.
+// Values are returned as strings, and may themselves be specs, such as
+// `threadsafe{inner:revolving{size:3}}`.  Surrounding whitespace is removed
+// from values, unless they are double-quoted, such as `separators:", "`.
+// Quoted values may contain commas and braces, and are unquoted as Go string
+// literals.
+func ParseSpec(spec string) (string, Params, error) {
+	spec = strings.TrimSpace(spec)
+	params := Params{}
+
+	open := strings.IndexByte(spec, '{')
+	if open < 0 {
+		if spec == "" {
+			return "", nil, fmt.Errorf("empty foundry spec")
+		}
+		return spec, params, nil
+	}
+	if !strings.HasSuffix(spec, "}") {
+		return "", nil, fmt.Errorf("foundry spec %q is missing a closing `}`", spec)
+	}
+
+	name := strings.TrimSpace(spec[:open])
+	if name == "" {
+		return "", nil, fmt.Errorf("foundry spec %q has no name", spec)
+	}
+
+	body := spec[open+1 : len(spec)-1]
+	for len(strings.TrimSpace(body)) > 0 {
+		// find the end of this key:value pair, skipping nested braces and
+		// quoted strings
+		depth, end := 0, len(body)
+		quoted, escaped := false, false
+	Scan:
+		for i, c := range body {
+			switch {
+			case escaped:
+				escaped = false
+			case quoted && c == '\\':
+				escaped = true
+			case c == '"':
+				quoted = !quoted
+			case quoted:
+			case c == '{':
+				depth++
+			case c == '}':
+				depth--
+				if depth < 0 {
+					return "", nil, fmt.Errorf("foundry spec %q has unbalanced braces", spec)
+				}
+			case c == ',' && depth == 0:
+				end = i
+				break Scan
+			}
+		}
+		if quoted {
+			return "", nil, fmt.Errorf("foundry spec %q has an unterminated quoted value", spec)
+		}
+		if depth != 0 {
+			return "", nil, fmt.Errorf("foundry spec %q has unbalanced braces", spec)
+		}
+
+		pair := body[:end]
+		if end < len(body) {
+			body = body[end+1:]
+		} else {
+			body = ""
+		}
+
+		colon := strings.IndexByte(pair, ':')
+		if colon < 0 {
+			return "", nil, fmt.Errorf("foundry spec %q has a parameter without a value: %q", spec, pair)
+		}
+		key := strings.TrimSpace(pair[:colon])
+		if key == "" {
+			return "", nil, fmt.Errorf("foundry spec %q has a parameter without a name", spec)
+		}
+		value := strings.TrimSpace(pair[colon+1:])
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return "", nil, fmt.Errorf("foundry spec %q has an invalid quoted value %s", spec, value)
+			}
+			value = unquoted
+		}
+		params[key] = value
+	}
+
+	return name, params, nil
+}
@@ -0,0 +1,162 @@
+package ident
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSpec(t *testing.T) {
+	name, params, err := ParseSpec("intern")
+	require.NoError(t, err)
+	require.Equal(t, "intern", name)
+	require.Equal(t, Params{}, params)
+
+	name, params, err = ParseSpec(" revolving{size:3, rotateAfter: 5000} ")
+	require.NoError(t, err)
+	require.Equal(t, "revolving", name)
+	require.Equal(t, Params{"size": "3", "rotateAfter": "5000"}, params)
+
+	name, params, err = ParseSpec("threadsafe{inner:revolving{size:3,rotateAfter:10}}")
+	require.NoError(t, err)
+	require.Equal(t, "threadsafe", name)
+	require.Equal(t, Params{"inner": "revolving{size:3,rotateAfter:10}"}, params)
+
+	name, params, err = ParseSpec("intern{}")
+	require.NoError(t, err)
+	require.Equal(t, "intern", name)
+	require.Equal(t, Params{}, params)
+
+	name, params, err = ParseSpec(`null{separators:", ", b: " x{}" ,c:"\"\t"}`)
+	require.NoError(t, err)
+	require.Equal(t, "null", name)
+	require.Equal(t, Params{"separators": ", ", "b": " x{}", "c": "\"\t"}, params)
+
+	// quoted values in nested specs are unquoted when the nested spec is parsed
+	_, params, err = ParseSpec(`threadsafe{inner:null{separators:",}"}}`)
+	require.NoError(t, err)
+	require.Equal(t, Params{"inner": `null{separators:",}"}`}, params)
+
+	for _, bad := range []string{"", "{size:3}", "revolving{size:3", "revolving{size}", "revolving{:3}", "a{b:{}",
+		`a{b:"c}`, `a{b:"c"d}`} {
+		_, _, err = ParseSpec(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestParamsInt(t *testing.T) {
+	p := Params{"a": 3, "b": int64(4), "c": 5.0, "d": "6", "e": 1.5, "f": "x", "g": true}
+
+	for key, exp := range map[string]int{"a": 3, "b": 4, "c": 5, "d": 6, "missing": 10} {
+		got, err := p.Int(key, 10)
+		require.NoError(t, err)
+		require.Equal(t, exp, got, key)
+	}
+
+	for _, key := range []string{"e", "f", "g"} {
+		_, err := p.Int(key, 10)
+		require.Error(t, err, key)
+	}
+}
+
+func TestParamsBool(t *testing.T) {
+	p := Params{"a": true, "b": "false", "c": "1", "d": "x", "e": 1}
+
+	for key, exp := range map[string]bool{"a": true, "b": false, "c": true, "missing": true} {
+		got, err := p.Bool(key, true)
+		require.NoError(t, err)
+		require.Equal(t, exp, got, key)
+	}
+
+	for _, key := range []string{"d", "e"} {
+		_, err := p.Bool(key, false)
+		require.Error(t, err, key)
+	}
+}
+
+func TestParamsApply(t *testing.T) {
+	p := Params{"a": 1, "b": "2", "c": true, "d": "x"}
+
+	var got []int
+	record := func(v int) { got = append(got, v) }
+	require.NoError(t, p.ApplyInts(IntParam{"b", record}, IntParam{"missing", record}, IntParam{"a", record}))
+	require.Equal(t, []int{2, 1}, got)
+
+	// the first error, in order, is returned
+	for i := 0; i < 10; i++ {
+		err := p.ApplyInts(IntParam{"c", record}, IntParam{"d", record})
+		require.EqualError(t, err, "parameter c must be an integer, got bool")
+	}
+
+	var flag bool
+	require.NoError(t, p.ApplyBools(BoolParam{"c", func(v bool) { flag = v }}))
+	require.True(t, flag)
+	require.Error(t, p.ApplyBools(BoolParam{"d", func(v bool) { flag = v }}))
+}
+
+func TestParamsDuration(t *testing.T) {
+	p := Params{"a": "1m30s", "b": time.Second, "c": 1000, "d": "soon", "e": true, "f": "600"}
+
+	for key, exp := range map[string]time.Duration{"a": 90 * time.Second, "b": time.Second, "missing": time.Hour} {
+		got, err := p.Duration(key, time.Hour)
+		require.NoError(t, err)
+		require.Equal(t, exp, got, key)
+	}
+
+	// bare numbers, whose unit is ambiguous, are rejected
+	for _, key := range []string{"c", "d", "e", "f"} {
+		_, err := p.Duration(key, 0)
+		require.Error(t, err, key)
+	}
+}
+
+func TestParamsStrings(t *testing.T) {
+	p := Params{"a": "x,y", "b": []interface{}{"x", "y"}, "c": []interface{}{1}}
+
+	got, err := p.Strings("a")
+	require.NoError(t, err)
+	require.Equal(t, []string{"x", "y"}, got)
+
+	got, err = p.Strings("b")
+	require.NoError(t, err)
+	require.Equal(t, []string{"x", "y"}, got)
+
+	_, err = p.Strings("c")
+	require.Error(t, err)
+
+	got, err = p.Strings("missing")
+	require.NoError(t, err)
+	require.Nil(t, got)
+}
+
+func TestParamsSpec(t *testing.T) {
+	p := Params{
+		"str": "revolving{size:3}",
+		"map": map[string]interface{}{"name": "revolving", "size": 3},
+		"bad": map[string]interface{}{"size": 3},
+	}
+
+	name, params, err := p.Spec("str", "")
+	require.NoError(t, err)
+	require.Equal(t, "revolving", name)
+	require.Equal(t, Params{"size": "3"}, params)
+
+	name, params, err = p.Spec("map", "")
+	require.NoError(t, err)
+	require.Equal(t, "revolving", name)
+	require.Equal(t, Params{"size": 3}, params)
+
+	name, _, err = p.Spec("missing", "intern")
+	require.NoError(t, err)
+	require.Equal(t, "intern", name)
+
+	_, _, err = p.Spec("bad", "")
+	require.Error(t, err)
+}
+
+func TestParamsCheck(t *testing.T) {
+	p := Params{"a": 1, "b": 2}
+	require.NoError(t, p.Check("a", "b", "c"))
+	require.EqualError(t, p.Check("a"), "unknown parameters: b")
+}
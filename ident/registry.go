@@ -0,0 +1,212 @@
+package ident
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// A Factory creates a foundry from a set of parameters.  Factories should
+// return an error for any parameters they do not recognize.
+type Factory func(params Params) (Foundry, error)
+
+var registry = struct {
+	sync.Mutex
+	factories map[string]Factory
+}{factories: map[string]Factory{}}
+
+// Register makes a foundry available by name, for use with New and
+// NewFromSpec.  It is typically called from an `init` function.  Register
+// panics if the name is already registered, or the factory is nil.
+func Register(name string, factory Factory) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if factory == nil {
+		panic("ident: Register factory is nil")
+	}
+	if _, dup := registry.factories[name]; dup {
+		panic("ident: Register called twice for foundry " + name)
+	}
+	registry.factories[name] = factory
+}
+
+// Registered returns the sorted names of all registered foundries.
+func Registered() []string {
+	registry.Lock()
+	defer registry.Unlock()
+
+	names := make([]string, 0, len(registry.factories))
+	for name := range registry.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates a foundry using the factory registered with the given name.
+func New(name string, params Params) (Foundry, error) {
+	registry.Lock()
+	factory, found := registry.factories[name]
+	registry.Unlock()
+
+	if !found {
+		return nil, fmt.Errorf("unknown ident foundry %q", name)
+	}
+	if params == nil {
+		params = Params{}
+	}
+
+	f, err := factory(params)
+	if err != nil {
+		return nil, fmt.Errorf("creating ident foundry %q: %w", name, err)
+	}
+	return f, nil
+}
+
+// NewFromSpec creates a foundry from a spec such as
+// `revolving{size:3,rotateAfter:5000}`.  See ParseSpec.
+func NewFromSpec(spec string) (Foundry, error) {
+	name, params, err := ParseSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	return New(name, params)
+}
+
+// The built-in foundries are registered with the following names and
+// parameters:
+//
+//	null
+//	intern{capacity}
+//	revolving{size, rotateAfter, capacity}
+//	threadsafe{inner}, where inner defaults to `intern`
+//	adaptive{window, maxEntries, capacity}
+//	frozen{tags, file, next}, where `tags` is a list and `file` is loaded as
+//	  by LoadFrozenFoundry; `next` is optional
+func init() {
+	Register("null", newNullFoundryFromParams)
+	Register("intern", newInternFoundryFromParams)
+	Register("revolving", newRevolvingFoundryFromParams)
+	Register("threadsafe", newThreadsafeFoundryFromParams)
+	Register("adaptive", newAdaptiveFoundryFromParams)
+	Register("frozen", newFrozenFoundryFromParams)
+}
+
+// intOption returns an IntParam that appends the Option for the parameter's
+// value to opts
+func intOption(opts *[]Option, key string, opt func(int) Option) IntParam {
+	return IntParam{key, func(v int) { *opts = append(*opts, opt(v)) }}
+}
+
+func newNullFoundryFromParams(params Params) (Foundry, error) {
+	if err := params.Check(); err != nil {
+		return nil, err
+	}
+	return NewNullFoundry()
+}
+
+func newInternFoundryFromParams(params Params) (Foundry, error) {
+	if err := params.Check("capacity"); err != nil {
+		return nil, err
+	}
+	var opts []Option
+	if err := params.ApplyInts(
+		intOption(&opts, "capacity", WithCapacity),
+	); err != nil {
+		return nil, err
+	}
+	return NewInternFoundry(opts...)
+}
+
+func newRevolvingFoundryFromParams(params Params) (Foundry, error) {
+	if err := params.Check("size", "rotateAfter", "capacity"); err != nil {
+		return nil, err
+	}
+	var opts []Option
+	if err := params.ApplyInts(
+		intOption(&opts, "size", WithGenerations),
+		intOption(&opts, "rotateAfter", WithRotateAfter),
+		intOption(&opts, "capacity", WithCapacity),
+	); err != nil {
+		return nil, err
+	}
+	return NewRevolvingFoundry(opts...)
+}
+
+func newThreadsafeFoundryFromParams(params Params) (Foundry, error) {
+	if err := params.Check("inner"); err != nil {
+		return nil, err
+	}
+	innerName, innerParams, err := params.Spec("inner", "intern")
+	if err != nil {
+		return nil, err
+	}
+	inner, err := New(innerName, innerParams)
+	if err != nil {
+		return nil, err
+	}
+	return NewThreadsafeFoundry(inner)
+}
+
+func newAdaptiveFoundryFromParams(params Params) (Foundry, error) {
+	if err := params.Check("window", "maxEntries", "capacity"); err != nil {
+		return nil, err
+	}
+	var opts []Option
+	if err := params.ApplyInts(
+		intOption(&opts, "window", WithWindow),
+		intOption(&opts, "maxEntries", WithMaxEntries),
+		intOption(&opts, "capacity", WithCapacity),
+	); err != nil {
+		return nil, err
+	}
+	return NewAdaptiveFoundry(opts...)
+}
+
+func newFrozenFoundryFromParams(params Params) (Foundry, error) {
+	if err := params.Check("tags", "file", "next"); err != nil {
+		return nil, err
+	}
+
+	var next Foundry
+	if params.Has("next") {
+		nextName, nextParams, err := params.Spec("next", "")
+		if err != nil {
+			return nil, err
+		}
+		next, err = New(nextName, nextParams)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tags, err := params.Strings("tags")
+	if err != nil {
+		return nil, err
+	}
+	idents := make([][]byte, len(tags))
+	for i, t := range tags {
+		idents[i] = []byte(t)
+	}
+
+	file, err := params.String("file", "")
+	if err != nil {
+		return nil, err
+	}
+	if file != "" {
+		r, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		loaded, err := readIdents(r)
+		if err != nil {
+			return nil, err
+		}
+		idents = append(idents, loaded...)
+	}
+
+	return NewFrozenFoundry(idents, next)
+}
@@ -0,0 +1,95 @@
+package ident
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryBuiltins(t *testing.T) {
+	names := Registered()
+	for _, name := range []string{"adaptive", "frozen", "intern", "null", "revolving", "threadsafe"} {
+		require.Contains(t, names, name)
+	}
+}
+
+func TestNewFromSpec(t *testing.T) {
+	f, err := NewFromSpec("revolving{size:4,rotateAfter:10}")
+	require.NoError(t, err)
+	rf, ok := f.(*RevolvingFoundry)
+	require.True(t, ok)
+	require.Len(t, rf.inner, 4)
+	require.Equal(t, 10, rf.rotateAfter)
+
+	f, err = NewFromSpec("threadsafe{inner:revolving{size:2}}")
+	require.NoError(t, err)
+	tf, ok := f.(*ThreadsafeFoundry)
+	require.True(t, ok)
+	require.IsType(t, &RevolvingFoundry{}, tf.inner)
+
+	f, err = NewFromSpec("threadsafe")
+	require.NoError(t, err)
+	require.IsType(t, &InternFoundry{}, f.(*ThreadsafeFoundry).inner)
+
+	_, err = NewFromSpec("nosuch")
+	require.Error(t, err)
+
+	_, err = NewFromSpec("intern{bogus:1}")
+	require.Error(t, err)
+
+	_, err = NewFromSpec("revolving{size:1}")
+	require.Error(t, err)
+}
+
+func TestNewWithParams(t *testing.T) {
+	// as decoded from YAML
+	f, err := New("adaptive", Params{"window": 100, "maxEntries": 1000})
+	require.NoError(t, err)
+	af := f.(*AdaptiveFoundry)
+	require.Equal(t, 100, af.window)
+	require.Equal(t, 1000, af.maxEntries)
+
+	f, err = New("null", nil)
+	require.NoError(t, err)
+	require.IsType(t, &NullFoundry{}, f)
+
+	// with several bad parameters, the first in order is reported
+	for i := 0; i < 10; i++ {
+		_, err = New("revolving", Params{"size": 1, "rotateAfter": 0, "capacity": -1})
+		require.EqualError(t, err,
+			`creating ident foundry "revolving": generations must be at least 2, got 1`)
+	}
+}
+
+func TestNewFrozen(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tags")
+	require.NoError(t, os.WriteFile(file, []byte("host:foo\nenv:prod\n"), 0644))
+
+	f, err := New("frozen", Params{
+		"tags": []interface{}{"team:bar"},
+		"file": file,
+		"next": "intern",
+	})
+	require.NoError(t, err)
+	ff := f.(*FrozenFoundry)
+	require.Equal(t, 3, ff.Len())
+	require.IsType(t, &InternFoundry{}, ff.next)
+}
+
+func TestRegister(t *testing.T) {
+	Register("test-custom", func(params Params) (Foundry, error) {
+		return NewNullFoundry()
+	})
+	require.Contains(t, Registered(), "test-custom")
+
+	f, err := NewFromSpec("test-custom")
+	require.NoError(t, err)
+	require.IsType(t, &NullFoundry{}, f)
+
+	require.Panics(t, func() {
+		Register("test-custom", func(params Params) (Foundry, error) { return nil, nil })
+	})
+	require.Panics(t, func() { Register("test-nil", nil) })
+}
@@ -4,43 +4,59 @@ package ident
 // them, allowing identifiers which are no longer used to be freed.  The effect
 // is similar to a batched least-recently-used cache.
 type RevolvingFoundry struct {
+	capacity    int
 	rotateAfter int
 	count       int
 	inner       []*InternFoundry
+
+	// callbacks registered with OnRotate and OnEvict
+	onRotate []func()
+	onEvict  []func(Ident)
 }
 
-// Create a RevolvingFoundry of the given size (number of InternFoundries) and
-// rotating after the given number of accesses.
+// Create a RevolvingFoundry with the number of InternFoundries given by
+// WithGenerations (default 3), rotating after the number of accesses given
+// by WithRotateAfter (default 5000).  WithCapacity pre-sizes each generation.
 //
-// The size should typically be very small (single digits, at least 2), as it
-// impacts the time it takes to create a novel Ident.
+// The number of generations should typically be very small (single digits,
+// at least 2), as it impacts the time it takes to create a novel Ident.
 //
-// The `rotateAfter` parameter should be tuned so that `rotateAfter` times
-// `size - 1` approximates the cardinality of the identifiers being created.
-// For example, if the identifiers are hostnames and there are typically 10,000
-// hosts active at any time, then `rotateAfter = 5000` and `size = 3` are
-// good choices.
-func NewRevolvingFoundry(size, rotateAfter int) *RevolvingFoundry {
-	if size < 2 {
-		panic("size must be at least 2")
+// The `rotateAfter` option should be tuned so that `rotateAfter` times
+// `generations - 1` approximates the cardinality of the identifiers being
+// created.  For example, if the identifiers are hostnames and there are
+// typically 10,000 hosts active at any time, then `rotateAfter = 5000` and
+// `generations = 3` are good choices.
+func NewRevolvingFoundry(opts ...Option) (*RevolvingFoundry, error) {
+	o, err := applyOptions(opts, optCapacity, optGenerations, optRotateAfter)
+	if err != nil {
+		return nil, err
 	}
-	inner := make([]*InternFoundry, size)
-	for i, _ := range inner {
-		inner[i] = NewInternFoundry()
+
+	inner := make([]*InternFoundry, o.generations)
+	for i := range inner {
+		inner[i] = newInternFoundry(o.capacity)
 	}
 
 	return &RevolvingFoundry{
-		rotateAfter: rotateAfter,
+		capacity:    o.capacity,
+		rotateAfter: o.rotateAfter,
 		count:       0,
 		inner:       inner,
-	}
+	}, nil
 }
 
 func (f *RevolvingFoundry) Ident(ident []byte) Ident {
+	rv, _ := f.IdentHit(ident)
+	return rv
+}
+
+// IdentHit returns the Ident for the given byte slice, and whether it was
+// already interned in any generation.
+func (f *RevolvingFoundry) IdentHit(ident []byte) (Ident, bool) {
 	f.count++
 	hashH, hashL := hashIdent(ident)
 
-	if f.count > f.rotateAfter {
+	if f.rotateAfter > 0 && f.count > f.rotateAfter {
 		f.rotate()
 		f.count = 0
 	}
@@ -54,7 +70,7 @@ func (f *RevolvingFoundry) Ident(ident []byte) Ident {
 			if i > 0 {
 				f.inner[0].insert(hashH, hashL, hit)
 			}
-			return hit
+			return hit, true
 		}
 	}
 
@@ -62,14 +78,67 @@ func (f *RevolvingFoundry) Ident(ident []byte) Ident {
 	rv := newIdent(ident, hashH, hashL)
 	f.inner[0].insert(hashH, hashL, rv)
 
-	return rv
+	return rv, false
+}
+
+// Get returns the interned Ident with the given hash, or nil if there is none.
+// This does not count as an access for purposes of rotation.
+func (f *RevolvingFoundry) Get(hashH, hashL uint64) Ident {
+	for _, inner := range f.inner {
+		hit := inner.get(hashH, hashL)
+		if hit != nil {
+			return hit
+		}
+	}
+	return nil
+}
+
+// OnRotate registers a function to be called each time the foundry rotates,
+// after any OnEvict callbacks for that rotation.
+//
+// Callbacks registered with OnRotate and OnEvict run synchronously, within
+// the call to Ident that triggered the rotation, so they delay that call.
+// If the foundry is wrapped in a ThreadsafeFoundry, they run with its mutex
+// held.  Callbacks must not call back into the foundry (or any foundry
+// wrapping it), which would deadlock or modify the foundry mid-rotation.
+func (f *RevolvingFoundry) OnRotate(cb func()) {
+	f.onRotate = append(f.onRotate, cb)
+}
+
+// OnEvict registers a function to be called for each identifier that is no
+// longer interned, when it is dropped during a rotation.  Identifiers that
+// were used recently enough to appear in a newer generation are not evicted.
+// After this call, the foundry will return a new Ident for the same bytes, so
+// callers can use this to invalidate caches keyed on the evicted Ident.  See
+// OnRotate for the context in which callbacks run.
+func (f *RevolvingFoundry) OnEvict(cb func(Ident)) {
+	f.onEvict = append(f.onEvict, cb)
 }
 
 // Insert a new InternFoundry at the beginning of the rotation, dropping the
 // last foundry.
 func (f *RevolvingFoundry) rotate() {
+	dropped := f.inner[len(f.inner)-1]
+
 	newInner := make([]*InternFoundry, len(f.inner))
-	newInner[0] = NewInternFoundry()
+	newInner[0] = newInternFoundry(f.capacity)
 	copy(newInner[1:], f.inner[:len(f.inner)-1])
 	f.inner = newInner
+
+	if len(f.onEvict) > 0 {
+		dropped.byHash.forEach(func(ident Ident) {
+			// identifiers still present in another generation were not
+			// evicted
+			if f.Get(ident.HashH(), ident.HashL()) != nil {
+				return
+			}
+			for _, cb := range f.onEvict {
+				cb(ident)
+			}
+		})
+	}
+
+	for _, cb := range f.onRotate {
+		cb()
+	}
 }
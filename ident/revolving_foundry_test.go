@@ -2,13 +2,16 @@ package ident
 
 import (
 	"fmt"
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRevolvingFoundry(t *testing.T) {
-	f := NewRevolvingFoundry(3, 5)
+	f, err := NewRevolvingFoundry(WithGenerations(3), WithRotateAfter(5))
+	require.NoError(t, err)
 
 	// fill one inner with a's
 	var aIds []Ident
@@ -42,3 +45,49 @@ func TestRevolvingFoundry(t *testing.T) {
 	b0 := f.Ident([]byte("b:0"))
 	assert.True(t, &b0[0] == &bIds[0][0])
 }
+
+func TestRevolvingFoundryGet(t *testing.T) {
+	f, err := NewRevolvingFoundry(WithGenerations(2), WithRotateAfter(2))
+	require.NoError(t, err)
+
+	a := f.Ident([]byte("a"))
+	f.Ident([]byte("b"))
+	f.Ident([]byte("c")) // rotates
+
+	got := f.Get(a.Hash())
+	assert.True(t, &a[0] == &got[0])
+	assert.Nil(t, f.Get(0x123, 0x456))
+}
+
+func TestRevolvingFoundryCallbacks(t *testing.T) {
+	f, err := NewRevolvingFoundry(WithGenerations(2), WithRotateAfter(3))
+	require.NoError(t, err)
+
+	rotations := 0
+	f.OnRotate(func() { rotations++ })
+	var evicted []string
+	f.OnEvict(func(ident Ident) { evicted = append(evicted, string(ident.Bytes())) })
+
+	f.Ident([]byte("a"))
+	f.Ident([]byte("b"))
+	f.Ident([]byte("c"))
+	assert.Equal(t, 0, rotations)
+
+	// rotates; a, b, c move to the second generation
+	f.Ident([]byte("d"))
+	assert.Equal(t, 1, rotations)
+	assert.Empty(t, evicted)
+
+	// pull `a` forward into the first generation
+	f.Ident([]byte("a"))
+	f.Ident([]byte("e"))
+	f.Ident([]byte("f"))
+	assert.Equal(t, 1, rotations)
+
+	// rotates, dropping the generation containing a, b, c; but `a` was
+	// used recently and is not evicted
+	f.Ident([]byte("g"))
+	assert.Equal(t, 2, rotations)
+	sort.Strings(evicted)
+	assert.Equal(t, []string{"b", "c"}, evicted)
+}
@@ -0,0 +1,111 @@
+package ident
+
+import "github.com/djmitche/tagset/internal/hashtable"
+
+/* IMPLEMENTATION NOTES
+ *
+ * This is an open-addressing hash table keyed by the full 128-bit hash of an
+ * Ident.  The table size is always a power of two, and slots are found by
+ * linear probing from `hashL & mask`.  Each slot stores both hash halves
+ * inline next to the Ident, so a probe sequence only touches the slot array
+ * and never dereferences an Ident to compare hashes.
+ *
+ * The table grows (doubling) when it is more than 3/4 full, so probe
+ * sequences remain short; see the hashtable package.  Growth is only
+ * considered when a new key is added, not when an existing key is replaced.
+ * There is no deletion; callers that need to forget identifiers drop the
+ * whole table (see RevolvingFoundry).
+ */
+
+// An identTable maps 128-bit hashes to Idents.
+type identTable struct {
+	slots []identSlot
+	mask  uint64
+	count int
+}
+
+type identSlot struct {
+	hashH, hashL uint64
+	ident        Ident
+}
+
+// newIdentTable creates a new table, sized such that it can hold `capacity`
+// identifiers without growing.
+func newIdentTable(capacity int) *identTable {
+	size := hashtable.SizeFor(capacity)
+	return &identTable{
+		slots: make([]identSlot, size),
+		mask:  uint64(size - 1),
+	}
+}
+
+// get returns the Ident with the given hash, or nil if it is not present.
+func (tbl *identTable) get(hashH, hashL uint64) Ident {
+	for i := hashL & tbl.mask; ; i = (i + 1) & tbl.mask {
+		slot := &tbl.slots[i]
+		if slot.ident == nil {
+			return nil
+		}
+		if slot.hashL == hashL && slot.hashH == hashH {
+			return slot.ident
+		}
+	}
+}
+
+// insert adds an Ident to the table, replacing any Ident with the same hash.
+func (tbl *identTable) insert(hashH, hashL uint64, ident Ident) {
+	i := hashL & tbl.mask
+	for ; tbl.slots[i].ident != nil; i = (i + 1) & tbl.mask {
+		slot := &tbl.slots[i]
+		if slot.hashL == hashL && slot.hashH == hashH {
+			slot.ident = ident
+			return
+		}
+	}
+
+	// this is a new key, so grow first if necessary
+	if hashtable.MustGrow(tbl.count, len(tbl.slots)) {
+		tbl.grow()
+		i = tbl.emptySlot(hashL)
+	}
+	tbl.slots[i] = identSlot{hashH: hashH, hashL: hashL, ident: ident}
+	tbl.count++
+}
+
+// len returns the number of identifiers in the table.
+func (tbl *identTable) len() int {
+	return tbl.count
+}
+
+// forEach calls the given function for each identifier in the table, in no
+// particular order.
+func (tbl *identTable) forEach(f func(Ident)) {
+	for i := range tbl.slots {
+		if tbl.slots[i].ident != nil {
+			f(tbl.slots[i].ident)
+		}
+	}
+}
+
+// grow doubles the size of the table, re-inserting all existing entries.
+func (tbl *identTable) grow() {
+	old := tbl.slots
+	tbl.slots = make([]identSlot, len(old)*2)
+	tbl.mask = uint64(len(tbl.slots) - 1)
+
+	for _, slot := range old {
+		if slot.ident != nil {
+			tbl.slots[tbl.emptySlot(slot.hashL)] = slot
+		}
+	}
+}
+
+// emptySlot returns the index of the first empty slot in the probe sequence
+// for the given hash.
+func (tbl *identTable) emptySlot(hashL uint64) uint64 {
+	i := hashL & tbl.mask
+	for tbl.slots[i].ident != nil {
+		i = (i + 1) & tbl.mask
+	}
+	return i
+}
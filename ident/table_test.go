@@ -0,0 +1,148 @@
+package ident
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentTable(t *testing.T) {
+	tbl := newIdentTable(0)
+	idents := map[string]Ident{}
+
+	// insert enough to force the table to grow several times
+	for i := 0; i < 1000; i++ {
+		id := makeIdent(fmt.Sprintf("id:%d", i))
+		require.Nil(t, tbl.get(id.Hash()))
+		tbl.insert(id.HashH(), id.HashL(), id)
+		idents[string(id.Bytes())] = id
+	}
+	require.Equal(t, 1000, tbl.len())
+
+	for _, id := range idents {
+		got := tbl.get(id.Hash())
+		require.True(t, &got[0] == &id[0])
+	}
+
+	seen := 0
+	tbl.forEach(func(id Ident) {
+		require.True(t, &idents[string(id.Bytes())][0] == &id[0])
+		seen++
+	})
+	require.Equal(t, 1000, seen)
+}
+
+func TestIdentTableCollisions(t *testing.T) {
+	tbl := newIdentTable(0)
+	id := makeIdent("abc")
+
+	// same HashL, different HashH, so these share a probe sequence
+	tbl.insert(1, 5, id)
+	tbl.insert(2, 5, id)
+	tbl.insert(3, 5, id)
+	require.Equal(t, 3, tbl.len())
+	require.NotNil(t, tbl.get(1, 5))
+	require.NotNil(t, tbl.get(2, 5))
+	require.NotNil(t, tbl.get(3, 5))
+	require.Nil(t, tbl.get(4, 5))
+
+	// re-inserting replaces
+	id2 := makeIdent("def")
+	tbl.insert(2, 5, id2)
+	require.Equal(t, 3, tbl.len())
+	got := tbl.get(2, 5)
+	require.True(t, &got[0] == &id2[0])
+}
+
+func TestIdentTableReplaceDoesNotGrow(t *testing.T) {
+	tbl := newIdentTable(0)
+	id := makeIdent("abc")
+
+	// fill the table to its maximum load factor
+	size := len(tbl.slots)
+	for i := uint64(0); i < uint64(size*3/4); i++ {
+		tbl.insert(i, i, id)
+	}
+	require.Equal(t, size, len(tbl.slots))
+
+	// replacing an existing key does not grow the table
+	tbl.insert(0, 0, id)
+	require.Equal(t, size, len(tbl.slots))
+
+	// adding a new key does
+	tbl.insert(100, 100, id)
+	require.Equal(t, size*2, len(tbl.slots))
+	require.Equal(t, size*3/4+1, tbl.len())
+}
+
+// global place for benchmarks to write, to avoid optimization
+var benchIdent Ident
+
+// identBenchCount is the number of identifiers in the tables used for lookup
+// benchmarks
+const identBenchCount = 100000
+
+// heapInUse returns the current heap allocation, after a GC
+func heapInUse() uint64 {
+	var ms runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&ms)
+	return ms.HeapAlloc
+}
+
+func benchIdents() []Ident {
+	idents := make([]Ident, identBenchCount)
+	for i := range idents {
+		idents[i] = makeIdent(fmt.Sprintf("bench:%d", i))
+	}
+	return idents
+}
+
+func BenchmarkIdentTableGet(b *testing.B) {
+	idents := benchIdents()
+
+	before := heapInUse()
+	tbl := newIdentTable(0)
+	for _, id := range idents {
+		tbl.insert(id.HashH(), id.HashL(), id)
+	}
+	after := heapInUse()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		benchIdent = tbl.get(idents[i%identBenchCount].Hash())
+	}
+
+	b.ReportMetric(float64(after-before)/float64(tbl.len()), "B/entry")
+}
+
+// BenchmarkIdentMapGet measures lookups in a Go map storing each identifier
+// under both its HashH and HashL, as InternFoundry formerly did, for
+// comparison with BenchmarkIdentTableGet.
+func BenchmarkIdentMapGet(b *testing.B) {
+	idents := benchIdents()
+
+	before := heapInUse()
+	m := map[uint64]Ident{}
+	for _, id := range idents {
+		m[id.HashH()] = id
+		m[id.HashL()] = id
+	}
+	after := heapInUse()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		hashH, hashL := idents[i%identBenchCount].Hash()
+		if hit := m[hashH]; hit != nil && hit.HashL() == hashL && hit.HashH() == hashH {
+			benchIdent = hit
+		}
+	}
+
+	b.ReportMetric(float64(after-before)/float64(identBenchCount), "B/entry")
+}
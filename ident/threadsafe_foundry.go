@@ -1,6 +1,9 @@
 package ident
 
-import "sync"
+import (
+	"errors"
+	"sync"
+)
 
 // A ThreadsafeFoundry wraps another Foundry and applies locking to allow
 // concurrent access from multiple goroutines.
@@ -9,11 +12,14 @@ type ThreadsafeFoundry struct {
 	inner Foundry
 }
 
-func NewThreadsafeFoundry(inner Foundry) *ThreadsafeFoundry {
+func NewThreadsafeFoundry(inner Foundry) (*ThreadsafeFoundry, error) {
+	if inner == nil {
+		return nil, errors.New("inner foundry must not be nil")
+	}
 	return &ThreadsafeFoundry{
 		sync.Mutex{},
 		inner,
-	}
+	}, nil
 }
 
 func (f *ThreadsafeFoundry) Ident(ident []byte) Ident {
@@ -22,3 +28,29 @@ func (f *ThreadsafeFoundry) Ident(ident []byte) Ident {
 	f.Unlock()
 	return rv
 }
+
+// IdentHit returns the Ident for the given byte slice and whether the inner
+// foundry already had it, if the inner foundry is a HitFoundry.  Otherwise,
+// every identifier is reported as a miss.
+func (f *ThreadsafeFoundry) IdentHit(ident []byte) (Ident, bool) {
+	f.Lock()
+	defer f.Unlock()
+	if hitFoundry, ok := f.inner.(HitFoundry); ok {
+		return hitFoundry.IdentHit(ident)
+	}
+	return f.inner.Ident(ident), false
+}
+
+// Get looks up an identifier in the inner foundry, if it is a LookupFoundry,
+// and otherwise returns nil.
+func (f *ThreadsafeFoundry) Get(hashH, hashL uint64) Ident {
+	lookup, ok := f.inner.(LookupFoundry)
+	if !ok {
+		return nil
+	}
+
+	f.Lock()
+	rv := lookup.Get(hashH, hashL)
+	f.Unlock()
+	return rv
+}
@@ -8,7 +8,8 @@ import (
 )
 
 func TestThreadsafeFoundry(t *testing.T) {
-	f := NewThreadsafeFoundry(NewInternFoundry())
+	f, err := NewThreadsafeFoundry(Must(NewInternFoundry()))
+	require.NoError(t, err)
 
 	var id1, id2 Ident
 	wg := sync.WaitGroup{}
@@ -27,3 +28,19 @@ func TestThreadsafeFoundry(t *testing.T) {
 	// InternFoundry should have deduplicated these
 	require.True(t, &id1[0] == &id2[0])
 }
+
+func TestThreadsafeFoundryGet(t *testing.T) {
+	f, err := NewThreadsafeFoundry(Must(NewInternFoundry()))
+	require.NoError(t, err)
+
+	id1 := f.Ident([]byte("abc:def"))
+	id2 := f.Get(id1.Hash())
+	require.True(t, &id1[0] == &id2[0])
+	require.Nil(t, f.Get(0x123, 0x456))
+
+	// inner foundries without Get always miss
+	f, err = NewThreadsafeFoundry(identOnlyFoundry{Must(NewInternFoundry())})
+	require.NoError(t, err)
+	id1 = f.Ident([]byte("abc:def"))
+	require.Nil(t, f.Get(id1.Hash()))
+}
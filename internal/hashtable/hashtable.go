@@ -0,0 +1,30 @@
+// Package hashtable contains the sizing rules shared by the open-addressing
+// hash tables in the ident and tagset packages.  Those tables have power-of-two
+// sizes, use linear probing, and grow (doubling) when they are more than 3/4
+// full.
+package hashtable
+
+// MinSize is the smallest number of slots in a table
+const MinSize = 8
+
+// SizeFor calculates the power-of-two table size required to hold `capacity`
+// entries without exceeding the maximum load factor.
+func SizeFor(capacity int) int {
+	size := MinSize
+	for !fits(capacity, size) {
+		size *= 2
+	}
+	return size
+}
+
+// MustGrow returns true if a table with `size` slots, holding `count`
+// entries, must grow before another entry is added.
+func MustGrow(count, size int) bool {
+	return !fits(count+1, size)
+}
+
+// fits returns true if `count` entries fit in `size` slots within the maximum
+// load factor
+func fits(count, size int) bool {
+	return count*4 <= size*3
+}
@@ -0,0 +1,26 @@
+package hashtable
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSizeFor(t *testing.T) {
+	require.Equal(t, MinSize, SizeFor(0))
+	require.Equal(t, 8, SizeFor(6))
+	require.Equal(t, 16, SizeFor(7))
+	require.Equal(t, 16, SizeFor(12))
+	require.Equal(t, 2048, SizeFor(1000))
+}
+
+func TestMustGrow(t *testing.T) {
+	require.False(t, MustGrow(0, 8))
+	require.False(t, MustGrow(5, 8))
+	require.True(t, MustGrow(6, 8))
+
+	// a table sized for a capacity holds that many entries without growing
+	for capacity := 0; capacity < 100; capacity++ {
+		require.False(t, MustGrow(capacity-1, SizeFor(capacity)), capacity)
+	}
+}
@@ -0,0 +1,103 @@
+package tagset
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// SerializationOptions parameterize AppendSerializationWith and
+// WriteSerializationTo.  The zero value produces the same output as
+// Serialization.
+type SerializationOptions struct {
+	// Prefix is written before the first tag.  Nothing is written for an
+	// empty TagSet.
+	Prefix []byte
+
+	// Separator is written between tags.  If nil, a comma is used.
+	Separator []byte
+
+	// Escape, if not nil, is used to append each tag, such as to quote it.
+	// AppendJSONString is suitable for JSON.
+	Escape func(dst []byte, tag []byte) []byte
+}
+
+// AppendSerialization appends the serialization of this tagset to dst, and
+// returns the extended slice.  If the serialization has already been
+// computed, it is copied; otherwise, this avoids the intermediate buffer of
+// Serialization.
+func (ts *TagSet) AppendSerialization(dst []byte) []byte {
+	if atomic.LoadUint32(&ts.serialized) != 0 {
+		return append(dst, ts.serialization...)
+	}
+	return ts.appendTags(dst)
+}
+
+// appendTags appends the comma-separated tags of this tagset to dst
+func (ts *TagSet) appendTags(dst []byte) []byte {
+	for i, t := range ts.tags {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, t.Bytes()...)
+	}
+	return dst
+}
+
+// AppendSerializationWith appends a serialization of this tagset to dst,
+// parameterized by the given options, and returns the extended slice.
+func (ts *TagSet) AppendSerializationWith(dst []byte, opts SerializationOptions) []byte {
+	if len(ts.tags) == 0 {
+		return dst
+	}
+
+	separator := opts.Separator
+	if separator == nil {
+		separator = commaSeparator
+	}
+
+	dst = append(dst, opts.Prefix...)
+	for i, t := range ts.tags {
+		if i > 0 {
+			dst = append(dst, separator...)
+		}
+		if opts.Escape != nil {
+			dst = opts.Escape(dst, t.Bytes())
+		} else {
+			dst = append(dst, t.Bytes()...)
+		}
+	}
+	return dst
+}
+
+// WriteTo writes the serialization of this tagset to w, implementing
+// io.WriterTo.  The serialization is written in a single call to w.Write.
+func (ts *TagSet) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(ts.Serialization())
+	return int64(n), err
+}
+
+// maxPooledWriteBuf is the largest buffer returned to writeBufPool, so that
+// an occasional very large tagset does not pin a large buffer in the pool
+const maxPooledWriteBuf = 64 * 1024
+
+// buffers for WriteSerializationTo
+var writeBufPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, 1024)
+		return &buf
+	},
+}
+
+// WriteSerializationTo writes a serialization of this tagset to w,
+// parameterized by the given options, in a single call to w.Write.
+func (ts *TagSet) WriteSerializationTo(w io.Writer, opts SerializationOptions) (int64, error) {
+	bufp := writeBufPool.Get().(*[]byte)
+	buf := ts.AppendSerializationWith((*bufp)[:0], opts)
+	n, err := w.Write(buf)
+	if cap(buf) <= maxPooledWriteBuf {
+		*bufp = buf
+		writeBufPool.Put(bufp)
+	}
+	return int64(n), err
+}
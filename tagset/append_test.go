@@ -0,0 +1,123 @@
+package tagset
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendSerialization(t *testing.T) {
+	ts := tagsetOf("a", "b", "c")
+
+	buf := ts.AppendSerialization([]byte("tags="))
+	require.Equal(t, append([]byte("tags="), ts.Serialization()...), buf)
+	require.Equal(t, []byte("x"), emptyTagSet.AppendSerialization([]byte("x")))
+}
+
+func TestAppendSerializationLazy(t *testing.T) {
+	f, err := NewNullFoundry(WithSerialization(LazySerialization))
+	require.NoError(t, err)
+	ts := f.Parse(idFoundry, []byte("a,b,c"))
+
+	buf := make([]byte, 0, 64)
+	allocs := testing.AllocsPerRun(10, func() {
+		buf = ts.AppendSerialization(buf[:0])
+	})
+	require.Equal(t, float64(0), allocs)
+
+	// the cached serialization was not computed
+	require.Nil(t, ts.serialization)
+	require.Equal(t, serializationOf("a", "b", "c"), buf)
+}
+
+func TestAppendSerializationCached(t *testing.T) {
+	ts := tagsetOf("a", "b", "c")
+	ts.Serialization()
+
+	// once computed, the cached serialization is copied rather than rebuilt
+	ts.serialization = []byte("cached")
+	require.Equal(t, []byte("x,cached"), ts.AppendSerialization([]byte("x,")))
+}
+
+func TestAppendSerializationWith(t *testing.T) {
+	ts := tagsetOf("a", "b")
+	exp := bytes.Split(ts.Serialization(), []byte(","))
+
+	buf := ts.AppendSerializationWith(nil, SerializationOptions{})
+	require.Equal(t, ts.Serialization(), buf)
+
+	buf = ts.AppendSerializationWith([]byte("m"), SerializationOptions{
+		Prefix:    []byte("|#"),
+		Separator: []byte(", "),
+	})
+	require.Equal(t, "m|#"+string(exp[0])+", "+string(exp[1]), string(buf))
+
+	buf = ts.AppendSerializationWith(nil, SerializationOptions{Escape: AppendJSONString})
+	require.Equal(t, `"`+string(exp[0])+`","`+string(exp[1])+`"`, string(buf))
+
+	// nothing, not even the prefix, is written for an empty tagset
+	buf = emptyTagSet.AppendSerializationWith([]byte("m"), SerializationOptions{Prefix: []byte("|#")})
+	require.Equal(t, []byte("m"), buf)
+}
+
+func TestWriteTo(t *testing.T) {
+	ts := tagsetOf("a", "b", "c")
+	var _ io.WriterTo = ts
+
+	var w bytes.Buffer
+	n, err := ts.WriteTo(&w)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(ts.Serialization())), n)
+	require.Equal(t, ts.Serialization(), w.Bytes())
+}
+
+func TestWriteSerializationTo(t *testing.T) {
+	ts := tagsetOf("a", "b", "c")
+	opts := SerializationOptions{Prefix: []byte("|#"), Separator: []byte(";")}
+
+	var w bytes.Buffer
+	n, err := ts.WriteSerializationTo(&w, opts)
+	require.NoError(t, err)
+	require.Equal(t, int64(w.Len()), n)
+	require.Equal(t, ts.AppendSerializationWith(nil, opts), w.Bytes())
+}
+
+func TestWriteSerializationToLarge(t *testing.T) {
+	tags := make([]string, 0, 10000)
+	for i := 0; i < 10000; i++ {
+		tags = append(tags, fmt.Sprintf("tag%d:%s", i, strings.Repeat("x", 10)))
+	}
+	ts := tagsetOf(tags...)
+	require.Greater(t, len(ts.Serialization()), maxPooledWriteBuf)
+
+	// buffers too large for the pool are still written correctly, and
+	// smaller writes after them are unaffected
+	for _, ts := range []*TagSet{ts, tagsetOf("a"), ts} {
+		var w bytes.Buffer
+		_, err := ts.WriteSerializationTo(&w, SerializationOptions{})
+		require.NoError(t, err)
+		require.Equal(t, ts.Serialization(), w.Bytes())
+	}
+}
+
+// errWriter fails every write
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("uhoh")
+}
+
+func TestWriteErrors(t *testing.T) {
+	ts := tagsetOf("a")
+
+	_, err := ts.WriteTo(errWriter{})
+	require.Error(t, err)
+
+	_, err = ts.WriteSerializationTo(errWriter{}, SerializationOptions{})
+	require.Error(t, err)
+}
@@ -0,0 +1,168 @@
+package tagset
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/djmitche/tagset/ident"
+)
+
+// An ArenaFoundry produces HandleSets, using an ident.ArenaFoundry to store
+// tags.  This is an alternative to the Foundry interface for applications
+// that wish to minimize the number of pointers the garbage collector must
+// scan.  An ArenaFoundry is not threadsafe and must not be accessed
+// concurrently.
+type ArenaFoundry struct {
+	arena *ident.ArenaFoundry
+
+	// if true, compute serializations when HandleSets are created
+	eager bool
+
+	// splitter for Parse
+	splitter *tagSplitter
+
+	// the empty set for this arena
+	empty *HandleSet
+}
+
+// Create an ArenaFoundry storing tags in the given arena.  The
+// WithSerialization option determines when serializations are computed, and
+// the WithParseOptions option configures Parse.
+func NewArenaFoundry(arena *ident.ArenaFoundry, opts ...Option) (*ArenaFoundry, error) {
+	if arena == nil {
+		return nil, errors.New("arena must not be nil")
+	}
+	o, err := applyOptions(opts, optSerialization, optParseOptions)
+	if err != nil {
+		return nil, err
+	}
+	return &ArenaFoundry{
+		arena:    arena,
+		eager:    o.serialization == EagerSerialization,
+		splitter: newTagSplitter(o.parse),
+		empty: &HandleSet{
+			arena:         arena,
+			serialization: []byte{},
+		},
+	}, nil
+}
+
+// NewWithDuplicates creates a new HandleSet from a slice of handles that may
+// contain duplicates.
+//
+// The slice is modified in-place, but not retained, and the caller may
+// re-use it after passing it to this function.
+func (f *ArenaFoundry) NewWithDuplicates(handles []ident.Handle) *HandleSet {
+	if len(handles) == 0 {
+		return f.empty
+	}
+
+	f.sort(handles)
+
+	nondup := make([]ident.Handle, 0, len(handles))
+	var lastHandle ident.Handle
+	var hashH, hashL uint64
+	for _, h := range handles {
+		// handles are unique to their identifiers, so equal identifiers
+		// are adjacent with equal handles after sorting
+		if h == lastHandle {
+			continue
+		}
+		nondup = append(nondup, h)
+		lastHandle = h
+		thH, thL := f.arena.Hash(h)
+		hashH ^= thH
+		hashL ^= thL
+	}
+
+	return f.build(nondup, hashH, hashL)
+}
+
+// Parse generates a HandleSet from a buffer containing comma-separated tags,
+// or as configured by WithParseOptions.  The buffer is not retained, and the
+// caller may re-use it after passing it to this function.
+func (f *ArenaFoundry) Parse(rawTags []byte) *HandleSet {
+	if len(rawTags) == 0 {
+		return f.empty
+	}
+
+	handles := make([]ident.Handle, 0, f.splitter.sizeHint(rawTags))
+	f.splitter.split(rawTags, func(tag []byte) {
+		handles = append(handles, f.arena.Handle(tag))
+	})
+
+	return f.NewWithDuplicates(handles)
+}
+
+// Union combines two HandleSets into one, handling the case where duplicates
+// exist between the two sets.
+func (f *ArenaFoundry) Union(hs1 *HandleSet, hs2 *HandleSet) *HandleSet {
+	return f.merge(hs1, hs2, true)
+}
+
+// DisjointUnion combines two HandleSets into one with the assumption that the
+// two sets are disjoint (share no tags in common).
+//
+// The caller MUST ensure this is the case.
+func (f *ArenaFoundry) DisjointUnion(hs1 *HandleSet, hs2 *HandleSet) *HandleSet {
+	return f.merge(hs1, hs2, false)
+}
+
+// merge combines the sorted handles of two HandleSets, optionally skipping
+// handles that appear in both.
+func (f *ArenaFoundry) merge(hs1 *HandleSet, hs2 *HandleSet, dedup bool) *HandleSet {
+	h1, h2 := hs1.handles, hs2.handles
+	merged := make([]ident.Handle, 0, len(h1)+len(h2))
+
+	// the hash begins with both sets, and a handle appearing in both is
+	// removed from it by XOR, so it must be added back once
+	hashH, hashL := hs1.hashH^hs2.hashH, hs1.hashL^hs2.hashL
+
+	for len(h1) > 0 && len(h2) > 0 {
+		switch {
+		case dedup && h1[0] == h2[0]:
+			thH, thL := f.arena.Hash(h1[0])
+			hashH ^= thH
+			hashL ^= thL
+			merged = append(merged, h1[0])
+			h1, h2 = h1[1:], h2[1:]
+		case f.arena.Less(h2[0], h1[0]):
+			merged = append(merged, h2[0])
+			h2 = h2[1:]
+		default:
+			merged = append(merged, h1[0])
+			h1 = h1[1:]
+		}
+	}
+	merged = append(merged, h1...)
+	merged = append(merged, h2...)
+
+	return f.build(merged, hashH, hashL)
+}
+
+// build creates a new HandleSet from a sorted, duplicate-free slice of
+// handles, which is retained, and their combined hash.  The serialization is
+// computed now if the foundry is eager.
+func (f *ArenaFoundry) build(handles []ident.Handle, hashH, hashL uint64) *HandleSet {
+	if len(handles) == 0 {
+		return f.empty
+	}
+
+	hs := &HandleSet{
+		arena:   f.arena,
+		handles: handles,
+		hashH:   hashH,
+		hashL:   hashL,
+	}
+	if f.eager {
+		hs.Serialization()
+	}
+	return hs
+}
+
+// sort sorts handles by the hash of their identifiers
+func (f *ArenaFoundry) sort(handles []ident.Handle) {
+	sort.Slice(handles, func(i, j int) bool {
+		return f.arena.Less(handles[i], handles[j])
+	})
+}
@@ -0,0 +1,101 @@
+package tagset
+
+import (
+	"testing"
+
+	"github.com/djmitche/tagset/ident"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestArenaFoundry(t *testing.T) *ArenaFoundry {
+	arena, err := ident.NewArenaFoundry()
+	require.NoError(t, err)
+	f, err := NewArenaFoundry(arena)
+	require.NoError(t, err)
+	return f
+}
+
+func TestArenaFoundryParse(t *testing.T) {
+	f := newTestArenaFoundry(t)
+
+	hs := f.Parse([]byte{})
+	require.Equal(t, uint64(0), hs.HashH())
+	require.Equal(t, uint64(0), hs.HashL())
+	require.Equal(t, []byte{}, hs.Serialization())
+
+	hs = f.Parse([]byte("a,b,a,b,c,c"))
+	expH, expL := hashOf("a", "b", "c")
+	gotH, gotL := hs.Hash()
+	require.Equal(t, expH, gotH)
+	require.Equal(t, expL, gotL)
+	require.Len(t, hs.Handles(), 3)
+	require.Equal(t, serializationOf("a", "b", "c"), hs.Serialization())
+}
+
+func TestArenaFoundryUnion(t *testing.T) {
+	f := newTestArenaFoundry(t)
+
+	hs1 := f.Parse([]byte("a,b,c"))
+	hs2 := f.Parse([]byte("b,c,d,e"))
+	u := f.Union(hs1, hs2)
+
+	expH, expL := hashOf("a", "b", "c", "d", "e")
+	require.Equal(t, expH, u.HashH())
+	require.Equal(t, expL, u.HashL())
+	require.Len(t, u.Handles(), 5)
+	require.Equal(t, serializationOf("a", "b", "c", "d", "e"), u.Serialization())
+
+	// union is commutative
+	u2 := f.Union(hs2, hs1)
+	require.Equal(t, u.Handles(), u2.Handles())
+}
+
+func TestArenaFoundryDisjointUnion(t *testing.T) {
+	f := newTestArenaFoundry(t)
+
+	hs1 := f.Parse([]byte("a,b"))
+	hs2 := f.Parse([]byte("c,d"))
+	u := f.DisjointUnion(hs1, hs2)
+
+	expH, expL := hashOf("a", "b", "c", "d")
+	require.Equal(t, expH, u.HashH())
+	require.Equal(t, expL, u.HashL())
+	require.Equal(t, serializationOf("a", "b", "c", "d"), u.Serialization())
+
+	require.Equal(t, hs1, f.DisjointUnion(hs1, f.Parse([]byte{})))
+}
+
+func TestArenaFoundryParseOptions(t *testing.T) {
+	arena, err := ident.NewArenaFoundry()
+	require.NoError(t, err)
+	f, err := NewArenaFoundry(arena, WithParseOptions(ParseOptions{
+		Separators: []byte(", "),
+		SkipEmpty:  true,
+		StripHash:  true,
+		MaxTags:    3,
+	}))
+	require.NoError(t, err)
+
+	hs := f.Parse([]byte("#a, b,,c d"))
+	require.Len(t, hs.Handles(), 3)
+	require.Equal(t, serializationOf("a", "b", "c"), hs.Serialization())
+}
+
+func TestArenaFoundrySerialization(t *testing.T) {
+	arena, err := ident.NewArenaFoundry()
+	require.NoError(t, err)
+
+	lazy, err := NewArenaFoundry(arena)
+	require.NoError(t, err)
+	hs := lazy.Parse([]byte("a,b"))
+	require.Nil(t, hs.serialization)
+	require.Equal(t, serializationOf("a", "b"), hs.Serialization())
+
+	eager, err := NewArenaFoundry(arena, WithSerialization(EagerSerialization))
+	require.NoError(t, err)
+	hs = eager.Parse([]byte("a,b"))
+	require.Equal(t, serializationOf("a", "b"), hs.serialization)
+
+	_, err = NewArenaFoundry(arena, WithKeyDedup(FirstKeyWins))
+	require.Error(t, err)
+}
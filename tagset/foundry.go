@@ -2,10 +2,15 @@ package tagset
 
 import "github.com/djmitche/tagset/ident"
 
-// A Foundry produces tagsets.  In general, foundries are not threadsafe.
+// A Foundry produces tagsets.  In general, foundries are not threadsafe; see
+// ThreadsafeFoundry and ShardedFoundry for foundries which are.
+//
+// The tags in every TagSet are sorted by hash, so that unions can be
+// performed with a linear merge.
 type Foundry interface {
 	// NewWithDuplicates creates a new TagSet from a slice of tags that may
-	// contain duplicates.
+	// contain duplicates.  Foundries created with the WithKeyDedup option
+	// also remove tags with duplicate keys.
 	//
 	// The slice is modified in-place, but not retained, and the caller may
 	// re-use it after passing it to this function.
@@ -14,19 +19,29 @@ type Foundry interface {
 	// NewWithoutDuplicates creates a new TagSet containing the given tags.
 	//
 	// The caller MUST ensure that the set of tags contains no duplicates.  The
-	// slice of tags may be retained in the tagset and MUST not be modified
-	// after passing it to this function.
+	// slice of tags is sorted in-place and may be retained in the tagset, and
+	// MUST not be modified after passing it to this function.
 	NewWithoutDuplicates(tags []ident.Ident) *TagSet
 
-	// Parse generates a TagSet from a buffer containing comma-separated tags.
-	// It detects duplicate tags while parsing.  The buffer is not retained,
+	// Parse generates a TagSet from a buffer containing comma-separated tags,
+	// or as configured by WithParseOptions.  It detects duplicate tags while
+	// parsing.  The buffer is not retained,
 	// and the caller may re-use it after passing it to this function.
 	Parse(foundry ident.Foundry, rawTags []byte) *TagSet
 
+	// ParseChecked is like Parse, but checks the input for problems: more
+	// than MaxTags tags, more than MaxLength bytes, tags longer than
+	// MaxTagLength bytes, tags that are not valid UTF-8, and empty tags,
+	// with the limits given by WithParseOptions.  If any are found, it
+	// returns a *ParseError describing them.  The input is then rejected, and
+	// the TagSet is nil, unless ParseOptions.Truncate is set, in which case
+	// the TagSet contains only the tags without problems.
+	ParseChecked(foundry ident.Foundry, rawTags []byte) (*TagSet, error)
+
 	// Union combines two TagSets into one, handling the case where duplicates
-	// exist between the two tagsets.  This is much slower than DisjointUnion,
-	// so callers that can otherwise ensure disjointness should prefer
-	// DisjointUnion.
+	// exist between the two tagsets.  This is a bit slower than
+	// DisjointUnion, so callers that can otherwise ensure disjointness should
+	// prefer DisjointUnion.
 	Union(ts1 *TagSet, ts2 *TagSet) *TagSet
 
 	// DisjointUnion combines two TagSets into one with the assumption that the
@@ -34,4 +49,38 @@ type Foundry interface {
 	//
 	// The caller MUST ensure this is the case.
 	DisjointUnion(ts1 *TagSet, ts2 *TagSet) *TagSet
+
+	// UnionN combines any number of TagSets into one, handling the case where
+	// duplicates exist between them.  This is more efficient than repeated
+	// calls to Union.
+	UnionN(sets ...*TagSet) *TagSet
+
+	// DisjointUnionN combines any number of TagSets into one with the
+	// assumption that they are disjoint (no two share a tag).  This is more
+	// efficient than repeated calls to DisjointUnion.
+	//
+	// The caller MUST ensure this is the case.
+	DisjointUnionN(sets ...*TagSet) *TagSet
+
+	// Override returns a TagSet containing the tags in overrides, and those
+	// tags in base with keys that do not appear in overrides.  For example,
+	// overriding `service:web,env:prod` with `service:api` gives
+	// `service:api,env:prod`.
+	Override(base *TagSet, overrides *TagSet) *TagSet
+
+	// Intersect returns a TagSet containing the tags present in both TagSets.
+	Intersect(ts1 *TagSet, ts2 *TagSet) *TagSet
+
+	// Difference returns a TagSet containing the tags in ts1 that are not in
+	// ts2.
+	Difference(ts1 *TagSet, ts2 *TagSet) *TagSet
+
+	// Without returns a TagSet containing the tags in ts, except the given
+	// tags.  Tags not in ts are ignored.  The slice of tags is not modified
+	// or retained.
+	Without(ts *TagSet, tags ...ident.Ident) *TagSet
+
+	// Filter returns a TagSet containing the tags in ts for which keep
+	// returns true.
+	Filter(ts *TagSet, keep func(ident.Ident) bool) *TagSet
 }
@@ -0,0 +1,77 @@
+package tagset
+
+import (
+	"sync"
+
+	"github.com/djmitche/tagset/ident"
+)
+
+// A HandleSet is the pointer-free counterpart to a TagSet, produced by an
+// ArenaFoundry.  Its tags are Handles into an ident.ArenaFoundry, sorted by
+// hash, so the garbage collector need not scan each tag.  The bytes and
+// hashes of the tags are resolved through the arena.  Like TagSets,
+// HandleSets are immutable once created.
+type HandleSet struct {
+	// arena resolves the handles in this set
+	arena *ident.ArenaFoundry
+
+	// handles contains a duplicate-free list of the tags in this set,
+	// sorted by hash
+	handles []ident.Handle
+
+	// hashH and hashL contain the hash of all tags in the set, computed as
+	// for a TagSet.
+	hashH, hashL uint64
+
+	// serialization of this set, computed on first use by Serialization
+	// unless the foundry computed it eagerly
+	serializeOnce sync.Once
+	serialization []byte
+}
+
+// Hash returns the 128-bit hash of this set, high word first
+func (hs *HandleSet) Hash() (uint64, uint64) {
+	return hs.hashH, hs.hashL
+}
+
+// HashH returns the high 64 bits of this set's hash
+func (hs *HandleSet) HashH() uint64 {
+	return hs.hashH
+}
+
+// HashL returns the low 64 bits of this set's hash
+func (hs *HandleSet) HashL() uint64 {
+	return hs.hashL
+}
+
+// Serialization returns the serialization of this set. The returned value
+// MUST not be modified.  Depending on the foundry, the serialization may be
+// computed on first use; this is threadsafe, but resolving the handles reads
+// the arena, so it must not run concurrently with use of the ArenaFoundry.
+func (hs *HandleSet) Serialization() []byte {
+	hs.serializeOnce.Do(func() {
+		if hs.serialization != nil {
+			return
+		}
+		serialization := make([]byte, 0, len(hs.handles)*avgTagSize)
+		for i, h := range hs.handles {
+			if i > 0 {
+				serialization = append(serialization, ',')
+			}
+			serialization = append(serialization, hs.arena.Bytes(h)...)
+		}
+		hs.serialization = serialization
+	})
+	return hs.serialization
+}
+
+// Handles returns the handles in this set, sorted by hash.  The returned
+// value MUST not be modified.
+func (hs *HandleSet) Handles() []ident.Handle {
+	return hs.handles
+}
+
+// Arena returns the arena that resolves this set's handles.
+func (hs *HandleSet) Arena() *ident.ArenaFoundry {
+	return hs.arena
+}
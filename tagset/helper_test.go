@@ -5,7 +5,7 @@ import (
 	"github.com/twmb/murmur3"
 )
 
-var idFoundry = ident.NewInternFoundry()
+var idFoundry = ident.Must(ident.NewInternFoundry())
 
 func hashOf(tags ...string) (uint64, uint64) {
 	var hh, hl uint64
@@ -16,3 +16,18 @@ func hashOf(tags ...string) (uint64, uint64) {
 	}
 	return hh, hl
 }
+
+// tagsetOf creates a TagSet with the given tags, using a NullFoundry
+func tagsetOf(tags ...string) *TagSet {
+	idents := make([]ident.Ident, len(tags))
+	for i, t := range tags {
+		idents[i] = idFoundry.Ident([]byte(t))
+	}
+	return (&NullFoundry{}).NewWithDuplicates(idents)
+}
+
+// serializationOf returns the expected serialization of a set containing the
+// given tags, using a NullFoundry
+func serializationOf(tags ...string) []byte {
+	return tagsetOf(tags...).Serialization()
+}
@@ -14,27 +14,68 @@ type InternFoundry struct {
 
 	// TagSets indexed by the hash of their parse input; notably this is NOT
 	// the hash of the TagSet itself.
-	byParseHash twoChoice
+	byParseHash *tagsetTable
+
+	// Results of Union and DisjointUnion, or nil if disabled
+	unions *unionCache
+
+	// Canonical TagSets, indexed by their own hash, or nil if content
+	// interning is disabled
+	byHash *tagsetTable
 
 	// Count of parses, and misses in the byParseHash cache
 	Parses, ParseMisses uint64
+
+	// Count of unions of non-empty TagSets, and misses in the union cache
+	Unions, UnionMisses uint64
+
+	// Count of lookups of constructed TagSets in the byHash cache, and
+	// misses (new canonical TagSets)
+	ContentLookups, ContentMisses uint64
 }
 
-func NewInternFoundry() *InternFoundry {
-	return &InternFoundry{
-		byParseHash: newTwoChoice(),
+// Create an InternFoundry.  The WithCapacity option pre-sizes its caches, the
+// WithUnionCacheSize option enables and bounds a cache of unions, and the
+// WithContentInterning option enables content-addressed interning; other
+// options are as for NewNullFoundry.
+func NewInternFoundry(opts ...Option) (*InternFoundry, error) {
+	o, err := applyOptions(opts, optCapacity, optUnionCacheSize, optContentInterning,
+		optSerialization, optKeyDedup, optParseOptions)
+	if err != nil {
+		return nil, err
+	}
+	f := &InternFoundry{
+		NullFoundry: newNullFoundry(o),
+		byParseHash: newTagsetTable(o.capacity),
 	}
+	if o.unionCacheSize > 0 {
+		f.unions = newUnionCache(o.unionCacheSize)
+	}
+	if o.contentInterning {
+		f.byHash = newTagsetTable(o.capacity)
+	}
+	return f, nil
 }
 
 func (f *InternFoundry) NewWithDuplicates(tags []ident.Ident) *TagSet {
-	return f.NullFoundry.NewWithDuplicates(tags)
+	return f.canonical(f.NullFoundry.NewWithDuplicates(tags))
 }
 
 func (f *InternFoundry) NewWithoutDuplicates(tags []ident.Ident) *TagSet {
-	return f.NullFoundry.NewWithoutDuplicates(tags)
+	return f.canonical(f.NullFoundry.NewWithoutDuplicates(tags))
+}
+
+func (f *InternFoundry) ParseChecked(foundry ident.Foundry, rawTags []byte) (*TagSet, error) {
+	return f.parseChecked(f, foundry, rawTags)
 }
 
 func (f *InternFoundry) Parse(foundry ident.Foundry, rawTags []byte) *TagSet {
+	return f.cachedParse(rawTags, func() *TagSet {
+		return f.NullFoundry.Parse(foundry, rawTags)
+	})
+}
+
+func (f *InternFoundry) cachedParse(rawTags []byte, miss func() *TagSet) *TagSet {
 	f.Parses++
 	rawHashH, rawHashL := murmur3.Sum128(rawTags)
 	existing := f.byParseHash.get(rawHashH, rawHashL)
@@ -43,15 +84,82 @@ func (f *InternFoundry) Parse(foundry ident.Foundry, rawTags []byte) *TagSet {
 	}
 
 	f.ParseMisses++
-	fresh := f.NullFoundry.Parse(foundry, rawTags)
+	fresh := f.canonical(miss())
 	f.byParseHash.insert(rawHashH, rawHashL, fresh)
 	return fresh
 }
 
 func (f *InternFoundry) Union(ts1 *TagSet, ts2 *TagSet) *TagSet {
-	return f.NullFoundry.Union(ts1, ts2)
+	return f.cachedUnion(ts1, ts2, f.NullFoundry.Union)
 }
 
 func (f *InternFoundry) DisjointUnion(ts1 *TagSet, ts2 *TagSet) *TagSet {
-	return f.NullFoundry.DisjointUnion(ts1, ts2)
+	return f.cachedUnion(ts1, ts2, f.NullFoundry.DisjointUnion)
+}
+
+// cachedUnion looks up the union of two TagSets in the union cache, falling
+// back to the given function on a miss.  Union and DisjointUnion share the
+// cache, as they produce the same result for disjoint inputs.
+func (f *InternFoundry) cachedUnion(ts1 *TagSet, ts2 *TagSet, union func(*TagSet, *TagSet) *TagSet) *TagSet {
+	// unions with the empty set are trivial and need not be cached
+	if f.unions == nil || ts1.size == 0 || ts2.size == 0 {
+		return f.canonical(union(ts1, ts2))
+	}
+
+	f.Unions++
+	keyH, keyL := unionKey(ts1, ts2)
+	if existing := f.unions.get(keyH, keyL); existing != nil {
+		return existing
+	}
+
+	f.UnionMisses++
+	fresh := f.canonical(union(ts1, ts2))
+	f.unions.insert(keyH, keyL, fresh)
+	return fresh
+}
+
+func (f *InternFoundry) UnionN(sets ...*TagSet) *TagSet {
+	return f.canonical(f.NullFoundry.UnionN(sets...))
+}
+
+func (f *InternFoundry) DisjointUnionN(sets ...*TagSet) *TagSet {
+	return f.canonical(f.NullFoundry.DisjointUnionN(sets...))
+}
+
+func (f *InternFoundry) Override(base *TagSet, overrides *TagSet) *TagSet {
+	return f.canonical(f.NullFoundry.Override(base, overrides))
+}
+
+func (f *InternFoundry) Intersect(ts1 *TagSet, ts2 *TagSet) *TagSet {
+	return f.canonical(f.NullFoundry.Intersect(ts1, ts2))
+}
+
+func (f *InternFoundry) Difference(ts1 *TagSet, ts2 *TagSet) *TagSet {
+	return f.canonical(f.NullFoundry.Difference(ts1, ts2))
+}
+
+func (f *InternFoundry) Without(ts *TagSet, tags ...ident.Ident) *TagSet {
+	return f.canonical(f.NullFoundry.Without(ts, tags...))
+}
+
+func (f *InternFoundry) Filter(ts *TagSet, keep func(ident.Ident) bool) *TagSet {
+	return f.canonical(f.NullFoundry.Filter(ts, keep))
+}
+
+// canonical returns the canonical TagSet with the same content as ts, if
+// content interning is enabled, so that equal TagSets from this foundry are
+// the same pointer and share a serialization.  Otherwise, it returns ts.
+func (f *InternFoundry) canonical(ts *TagSet) *TagSet {
+	if f.byHash == nil || ts.size == 0 {
+		return ts
+	}
+
+	f.ContentLookups++
+	if existing := f.byHash.get(ts.hashH, ts.hashL); existing != nil {
+		return existing
+	}
+
+	f.ContentMisses++
+	f.byHash.insert(ts.hashH, ts.hashL, ts)
+	return ts
 }
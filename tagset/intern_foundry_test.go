@@ -1,19 +1,73 @@
-package tagset
+package tagset_test
 
 import (
 	"testing"
 
+	"github.com/djmitche/tagset/ident"
+	"github.com/djmitche/tagset/tagset"
+	"github.com/djmitche/tagset/tagset/tagsettest"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
 type InternFoundrySuite struct {
-	FoundrySuite
+	tagsettest.FoundrySuite
 }
 
 func TestInternFoundry(t *testing.T) {
 	suite.Run(t, &InternFoundrySuite{
-		FoundrySuite: FoundrySuite{
-			f: NewInternFoundry(),
+		FoundrySuite: tagsettest.FoundrySuite{
+			NewFoundry: func() tagset.Foundry { return tagset.Must(tagset.NewInternFoundry()) },
+			Interns:    true,
 		},
 	})
 }
+
+func TestInternFoundryEager(t *testing.T) {
+	suite.Run(t, &InternFoundrySuite{
+		FoundrySuite: tagsettest.FoundrySuite{
+			NewFoundry: func() tagset.Foundry {
+				return tagset.Must(tagset.NewInternFoundry(tagset.WithSerialization(tagset.EagerSerialization)))
+			},
+			Interns: true,
+		},
+	})
+}
+
+func TestInternFoundryUnionCache(t *testing.T) {
+	suite.Run(t, &InternFoundrySuite{
+		FoundrySuite: tagsettest.FoundrySuite{
+			NewFoundry: func() tagset.Foundry {
+				return tagset.Must(tagset.NewInternFoundry(tagset.WithUnionCacheSize(1000)))
+			},
+			Interns: true,
+		},
+	})
+}
+
+func TestInternFoundryContent(t *testing.T) {
+	suite.Run(t, &InternFoundrySuite{
+		FoundrySuite: tagsettest.FoundrySuite{
+			NewFoundry: func() tagset.Foundry {
+				return tagset.Must(tagset.NewInternFoundry(tagset.WithContentInterning(true)))
+			},
+			Interns:        true,
+			InternsContent: true,
+		},
+	})
+}
+
+func TestInternFoundryContentCounters(t *testing.T) {
+	idFoundry := ident.Must(ident.NewInternFoundry())
+	f, err := tagset.NewInternFoundry(tagset.WithContentInterning(true))
+	require.NoError(t, err)
+
+	ts1 := f.Parse(idFoundry, []byte("a,b"))
+	ts2 := f.Parse(idFoundry, []byte("b,a"))
+	require.True(t, ts1 == ts2)
+	require.Equal(t, uint64(2), f.ContentLookups)
+	require.Equal(t, uint64(1), f.ContentMisses)
+
+	// the shared TagSet is serialized once
+	require.True(t, &ts1.Serialization()[0] == &ts2.Serialization()[0])
+}
@@ -0,0 +1,110 @@
+package tagset
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/djmitche/tagset/ident"
+)
+
+// A tag's key is the portion of the tag before its first colon, so the key of
+// `env:prod` is `env`.  A tag without a colon is its own key.
+func tagKey(tag []byte) []byte {
+	if colon := bytes.IndexByte(tag, ':'); colon >= 0 {
+		return tag[:colon]
+	}
+	return tag
+}
+
+// A keyIndex supports lookups of tags by key.  It is built on first use, so
+// that repeated lookups need not scan every tag for a colon.
+type keyIndex struct {
+	// entries, sorted by key and then in hash order
+	entries []keyEntry
+
+	// distinct keys, in sorted order
+	keys [][]byte
+}
+
+type keyEntry struct {
+	// key is a slice of the tag's bytes
+	key []byte
+	tag ident.Ident
+}
+
+func newKeyIndex(tags []ident.Ident) *keyIndex {
+	idx := &keyIndex{
+		entries: make([]keyEntry, len(tags)),
+	}
+	for i, t := range tags {
+		idx.entries[i] = keyEntry{tagKey(t.Bytes()), t}
+	}
+	// tags are already in hash order, so a stable sort keeps that order
+	// among entries with the same key
+	sort.SliceStable(idx.entries, func(i, j int) bool {
+		return bytes.Compare(idx.entries[i].key, idx.entries[j].key) < 0
+	})
+
+	for i, e := range idx.entries {
+		if i == 0 || !bytes.Equal(e.key, idx.entries[i-1].key) {
+			idx.keys = append(idx.keys, e.key)
+		}
+	}
+	return idx
+}
+
+// find returns the entries with the given key
+func (idx *keyIndex) find(key []byte) []keyEntry {
+	entries := idx.entries
+	i := sort.Search(len(entries), func(i int) bool {
+		return bytes.Compare(entries[i].key, key) >= 0
+	})
+	j := i
+	for j < len(entries) && bytes.Equal(entries[j].key, key) {
+		j++
+	}
+	return entries[i:j]
+}
+
+// keyIndex returns the key index for this tagset, building it if necessary
+func (ts *TagSet) keyIndex() *keyIndex {
+	extra := ts.extra()
+	extra.keysOnce.Do(func() { extra.keys = newKeyIndex(ts.tags) })
+	return extra.keys
+}
+
+// Get returns a tag with the given key, such as `env:prod` for key `env`, and
+// true; or false if there is no such tag.  If there are several such tags,
+// the first in hash order is returned.
+func (ts *TagSet) Get(key []byte) (ident.Ident, bool) {
+	entries := ts.keyIndex().find(key)
+	if len(entries) == 0 {
+		return nil, false
+	}
+	return entries[0].tag, true
+}
+
+// GetAll returns all tags with the given key, in hash order, or nil if there
+// are none.
+func (ts *TagSet) GetAll(key []byte) []ident.Ident {
+	entries := ts.keyIndex().find(key)
+	if len(entries) == 0 {
+		return nil
+	}
+	tags := make([]ident.Ident, len(entries))
+	for i, e := range entries {
+		tags[i] = e.tag
+	}
+	return tags
+}
+
+// HasKey determines whether the tagset contains a tag with the given key
+func (ts *TagSet) HasKey(key []byte) bool {
+	return len(ts.keyIndex().find(key)) > 0
+}
+
+// Keys returns the distinct keys of the tags in this tagset, in sorted
+// order.  The returned slice and its contents MUST not be modified.
+func (ts *TagSet) Keys() [][]byte {
+	return ts.keyIndex().keys
+}
@@ -0,0 +1,90 @@
+package tagset
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagKey(t *testing.T) {
+	require.Equal(t, []byte("env"), tagKey([]byte("env:prod")))
+	require.Equal(t, []byte("url"), tagKey([]byte("url:http://x")))
+	require.Equal(t, []byte("bare"), tagKey([]byte("bare")))
+	require.Equal(t, []byte(""), tagKey([]byte(":value")))
+}
+
+func TestGet(t *testing.T) {
+	ts := tagsetOf("env:prod", "team:a", "team:b", "bare", "service:web")
+
+	tag, found := ts.Get([]byte("env"))
+	require.True(t, found)
+	require.Equal(t, []byte("env:prod"), tag.Bytes())
+
+	tag, found = ts.Get([]byte("bare"))
+	require.True(t, found)
+	require.Equal(t, []byte("bare"), tag.Bytes())
+
+	_, found = ts.Get([]byte("nope"))
+	require.False(t, found)
+
+	// a prefix of a key is not a key
+	_, found = ts.Get([]byte("serv"))
+	require.False(t, found)
+
+	// with several values, the first in hash order is returned
+	tag, found = ts.Get([]byte("team"))
+	require.True(t, found)
+	require.Equal(t, ts.GetAll([]byte("team"))[0], tag)
+
+	_, found = emptyTagSet.Get([]byte("env"))
+	require.False(t, found)
+}
+
+func TestGetAll(t *testing.T) {
+	ts := tagsetOf("env:prod", "team:a", "team:b", "team:c")
+
+	teams := ts.GetAll([]byte("team"))
+	require.Len(t, teams, 3)
+	strs := []string{}
+	for i, tag := range teams {
+		strs = append(strs, string(tag.Bytes()))
+		if i > 0 {
+			require.True(t, teams[i-1].Less(tag))
+		}
+	}
+	require.ElementsMatch(t, []string{"team:a", "team:b", "team:c"}, strs)
+
+	require.Len(t, ts.GetAll([]byte("env")), 1)
+	require.Nil(t, ts.GetAll([]byte("nope")))
+}
+
+func TestHasKey(t *testing.T) {
+	ts := tagsetOf("env:prod", "bare")
+	require.True(t, ts.HasKey([]byte("env")))
+	require.True(t, ts.HasKey([]byte("bare")))
+	require.False(t, ts.HasKey([]byte("prod")))
+	require.False(t, emptyTagSet.HasKey([]byte("env")))
+}
+
+func TestKeys(t *testing.T) {
+	ts := tagsetOf("team:b", "env:prod", "team:a", "bare")
+	require.Equal(t, [][]byte{[]byte("bare"), []byte("env"), []byte("team")}, ts.Keys())
+	require.Empty(t, emptyTagSet.Keys())
+}
+
+func TestKeysConcurrent(t *testing.T) {
+	ts := tagsetOf("env:prod", "team:a", "team:b")
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !ts.HasKey([]byte("team")) {
+				t.Errorf("missing key")
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -2,16 +2,60 @@ package tagset
 
 import (
 	"bytes"
+	"sort"
 
 	"github.com/djmitche/tagset/ident"
 )
 
 // A NullFoundry is the simplest possible Foundry: it just creates TagSets as
-// necessary.
-type NullFoundry struct{}
+// necessary.  The zero value is a NullFoundry with lazy serialization.
+type NullFoundry struct {
+	// if true, compute serializations when TagSets are created
+	eager bool
 
-func NewNullFoundry() *NullFoundry {
-	return &NullFoundry{}
+	// how NewWithDuplicates handles multiple tags with the same key
+	keyDedup KeyDedupMode
+
+	// splitter for Parse and ParseChecked, or nil for defaultSplitter
+	splitter *tagSplitter
+}
+
+// Create a NullFoundry.  The WithSerialization option determines when
+// serializations are computed, the WithKeyDedup option determines how
+// NewWithDuplicates and Parse handle tags with the same key, and the
+// WithParseOptions option configures Parse.
+func NewNullFoundry(opts ...Option) (*NullFoundry, error) {
+	o, err := applyOptions(opts, optSerialization, optKeyDedup, optParseOptions)
+	if err != nil {
+		return nil, err
+	}
+	f := newNullFoundry(o)
+	return &f, nil
+}
+
+// newNullFoundry creates a NullFoundry with the given options, for use
+// directly or embedded in another foundry.
+func newNullFoundry(o options) NullFoundry {
+	return NullFoundry{
+		eager:    o.serialization == EagerSerialization,
+		keyDedup: o.keyDedup,
+		splitter: newTagSplitter(o.parse),
+	}
+}
+
+// newTagSet creates a TagSet from sorted, duplicate-free tags, computing
+// its serialization now if the foundry is eager.
+func (f *NullFoundry) newTagSet(tags []ident.Ident, hashH, hashL uint64) *TagSet {
+	ts := &TagSet{
+		size:  len(tags),
+		tags:  tags,
+		hashH: hashH,
+		hashL: hashL,
+	}
+	if f.eager {
+		ts.Serialization()
+	}
+	return ts
 }
 
 func (f *NullFoundry) NewWithDuplicates(tags []ident.Ident) *TagSet {
@@ -19,11 +63,13 @@ func (f *NullFoundry) NewWithDuplicates(tags []ident.Ident) *TagSet {
 		return emptyTagSet
 	}
 
+	if f.keyDedup != KeepAllKeys {
+		tags = dedupKeys(tags, f.keyDedup)
+	}
+
 	ident.Sort(tags)
 
 	lastTag := tags[0]
-	serialization := make([]byte, 0, len(tags)*avgTagSize)
-	serialization = append(serialization, lastTag.Bytes()...)
 	nondup := make([]ident.Ident, 0, len(tags))
 	nondup = append(nondup, lastTag)
 	hashH := lastTag.HashH()
@@ -33,42 +79,24 @@ func (f *NullFoundry) NewWithDuplicates(tags []ident.Ident) *TagSet {
 			continue
 		}
 		nondup = append(nondup, t)
-		serialization = append(serialization, ',')
-		serialization = append(serialization, t.Bytes()...)
 		hashH ^= t.HashH()
 		hashL ^= t.HashL()
 		lastTag = t
 	}
 
-	return &TagSet{
-		size:          len(nondup),
-		tags:          nondup,
-		hashH:         hashH,
-		hashL:         hashL,
-		serialization: serialization,
-	}
+	return f.newTagSet(nondup, hashH, hashL)
 }
 
 func (f *NullFoundry) NewWithoutDuplicates(tags []ident.Ident) *TagSet {
-	var hashH, hashL uint64
-	serialization := make([]byte, 0, len(tags)*avgTagSize)
+	ident.Sort(tags)
 
+	var hashH, hashL uint64
 	for _, t := range tags {
-		if len(serialization) > 0 {
-			serialization = append(serialization, ',')
-		}
-		serialization = append(serialization, t.Bytes()...)
 		hashH ^= t.HashH()
 		hashL ^= t.HashL()
 	}
 
-	return &TagSet{
-		size:          len(tags),
-		tags:          tags,
-		hashH:         hashH,
-		hashL:         hashL,
-		serialization: serialization,
-	}
+	return f.newTagSet(tags, hashH, hashL)
 }
 
 func (f *NullFoundry) Parse(foundry ident.Foundry, rawTags []byte) *TagSet {
@@ -76,86 +104,285 @@ func (f *NullFoundry) Parse(foundry ident.Foundry, rawTags []byte) *TagSet {
 		return emptyTagSet
 	}
 
-	tagsCount := bytes.Count(rawTags, commaSeparator) + 1
-	tags := make([]ident.Ident, tagsCount)
-
-	for i := 0; i < tagsCount-1; i++ {
-		tagPos := bytes.Index(rawTags, commaSeparator)
-		if tagPos < 0 {
-			break
-		}
-		tags[i] = foundry.Ident(rawTags[:tagPos])
-		rawTags = rawTags[tagPos+len(commaSeparator):]
+	s := f.splitter
+	if s == nil {
+		s = defaultSplitter
 	}
-	tags[tagsCount-1] = foundry.Ident(rawTags)
+	tags := make([]ident.Ident, 0, s.sizeHint(rawTags))
+	s.split(rawTags, func(tag []byte) {
+		tags = append(tags, foundry.Ident(tag))
+	})
 
 	// just assume there were duplicates in the parse..
 	return f.NewWithDuplicates(tags)
 }
 
 func (f *NullFoundry) Union(ts1 *TagSet, ts2 *TagSet) *TagSet {
-	// Because these may not be disjoint, we allocate a new array of tags
-	// for the smaller of the two parents, and fill it with non-duplicate
-
-	// ensure t2 is smaller than t1
-	t1size, t2size := ts1.size, ts2.size
-	if t1size < t2size {
-		ts1, ts2 = ts2, ts1
-		t1size, t2size = t2size, t1size
-	}
-
-	hashH := ts1.hashH
-	hashL := ts1.hashL
-	clone := make([]ident.Ident, 0, t1size+t2size)
-	clone = append(clone, ts1.tags...)
-	serialization := make([]byte, 0, len(ts1.serialization)+len(ts2.serialization)+1)
-	serialization = append(serialization, ts1.serialization...)
-
-	// insert non-duplicate tags from ts2, updating the hash
-Outer:
-	for _, t2 := range ts2.tags {
-		for _, t1 := range ts1.tags {
-			if t1.Equals(t2) {
-				continue Outer
+	return f.merge(ts1, ts2, true)
+}
+
+func (f *NullFoundry) DisjointUnion(ts1 *TagSet, ts2 *TagSet) *TagSet {
+	return f.merge(ts1, ts2, false)
+}
+
+// merge combines the sorted tags of two TagSets in a single linear pass,
+// optionally skipping tags that appear in both.
+func (f *NullFoundry) merge(ts1 *TagSet, ts2 *TagSet, dedup bool) *TagSet {
+	if ts1.size == 0 {
+		return ts2
+	}
+	if ts2.size == 0 {
+		return ts1
+	}
+
+	t1, t2 := ts1.tags, ts2.tags
+	merged := make([]ident.Ident, 0, len(t1)+len(t2))
+
+	// the hash begins with all of ts1, and tags from ts2 are added as they
+	// are merged
+	hashH, hashL := ts1.hashH, ts1.hashL
+
+	for len(t1) > 0 && len(t2) > 0 {
+		switch {
+		case dedup && compareTags(t1[0], t2[0]) == 0:
+			merged = append(merged, t1[0])
+			t1, t2 = t1[1:], t2[1:]
+		case t2[0].Less(t1[0]):
+			hashH ^= t2[0].HashH()
+			hashL ^= t2[0].HashL()
+			merged = append(merged, t2[0])
+			t2 = t2[1:]
+		default:
+			merged = append(merged, t1[0])
+			t1 = t1[1:]
+		}
+	}
+	merged = append(merged, t1...)
+	for _, t := range t2 {
+		hashH ^= t.HashH()
+		hashL ^= t.HashL()
+	}
+	merged = append(merged, t2...)
+
+	return f.newTagSet(merged, hashH, hashL)
+}
+
+func (f *NullFoundry) UnionN(sets ...*TagSet) *TagSet {
+	return f.mergeN(sets, true)
+}
+
+func (f *NullFoundry) DisjointUnionN(sets ...*TagSet) *TagSet {
+	return f.mergeN(sets, false)
+}
+
+// mergeN combines the sorted tags of any number of TagSets in a single pass,
+// optionally skipping duplicate tags.  This allocates only the resulting
+// TagSet and its tags.
+func (f *NullFoundry) mergeN(sets []*TagSet, dedup bool) *TagSet {
+	// gather the tags of the non-empty sets; the number of sets is typically
+	// small, so avoid an allocation for it
+	var buf [8][]ident.Ident
+	heads := buf[:0]
+	var last *TagSet
+	size := 0
+	for _, ts := range sets {
+		if ts.size > 0 {
+			heads = append(heads, ts.tags)
+			size += ts.size
+			last = ts
+		}
+	}
+	switch len(heads) {
+	case 0:
+		return emptyTagSet
+	case 1:
+		return last
+	}
+
+	merged := make([]ident.Ident, 0, size)
+	var hashH, hashL uint64
+	for {
+		// find the head with the smallest tag; the number of sets is small
+		// enough that a linear search is faster than a heap
+		lowest := -1
+		for i, h := range heads {
+			if len(h) > 0 && (lowest < 0 || h[0].Less(heads[lowest][0])) {
+				lowest = i
 			}
 		}
-		hashH ^= t2.HashH()
-		hashL ^= t2.HashL()
-		clone = append(clone, t2)
-		serialization = append(append(serialization, byte(',')), t2.Bytes()...)
+		if lowest < 0 {
+			break
+		}
+
+		t := heads[lowest][0]
+		heads[lowest] = heads[lowest][1:]
+		if dedup && len(merged) > 0 && compareTags(merged[len(merged)-1], t) == 0 {
+			continue
+		}
+		merged = append(merged, t)
+		hashH ^= t.HashH()
+		hashL ^= t.HashL()
 	}
 
-	// since we prepended a `,` to every item, remove it if necessary
-	if len(serialization) > 0 && serialization[0] == byte(',') {
-		serialization = serialization[1:]
+	return f.newTagSet(merged, hashH, hashL)
+}
+
+// Override replaces any tag in base whose key appears in overrides with the
+// tags from overrides.
+func (f *NullFoundry) Override(base *TagSet, overrides *TagSet) *TagSet {
+	if overrides.size == 0 {
+		return base
 	}
+	keys := overrides.keyIndex()
+	kept := f.Filter(base, func(t ident.Ident) bool {
+		return len(keys.find(tagKey(t.Bytes()))) == 0
+	})
+	// kept shares no keys, and thus no tags, with overrides
+	return f.merge(kept, overrides, false)
+}
+
+func (f *NullFoundry) Intersect(ts1 *TagSet, ts2 *TagSet) *TagSet {
+	return f.subtract(ts1, ts2.tags, true)
+}
+
+func (f *NullFoundry) Difference(ts1 *TagSet, ts2 *TagSet) *TagSet {
+	return f.subtract(ts1, ts2.tags, false)
+}
+
+func (f *NullFoundry) Without(ts *TagSet, tags ...ident.Ident) *TagSet {
+	sorted := make([]ident.Ident, len(tags))
+	copy(sorted, tags)
+	ident.Sort(sorted)
+	return f.subtract(ts, sorted, false)
+}
 
-	return &TagSet{
-		size:          len(clone),
-		tags:          clone,
-		hashH:         hashH,
-		hashL:         hashL,
-		serialization: serialization,
+func (f *NullFoundry) Filter(ts *TagSet, keep func(ident.Ident) bool) *TagSet {
+	kept := make([]ident.Ident, 0, len(ts.tags))
+	var removedH, removedL uint64
+	for _, t := range ts.tags {
+		if keep(t) {
+			kept = append(kept, t)
+		} else {
+			removedH ^= t.HashH()
+			removedL ^= t.HashL()
+		}
 	}
+	return f.subset(ts, kept, removedH, removedL)
 }
 
-func (f *NullFoundry) DisjointUnion(ts1 *TagSet, ts2 *TagSet) *TagSet {
-	clone := make([]ident.Ident, 0, ts1.size+ts2.size)
-	clone = append(clone, ts1.tags...)
-	clone = append(clone, ts2.tags...)
+// subtract walks the tags of ts alongside the given sorted tags, keeping
+// those tags of ts that are (if `common`) or are not (otherwise) present in
+// both.
+func (f *NullFoundry) subtract(ts *TagSet, tags []ident.Ident, common bool) *TagSet {
+	t1, t2 := ts.tags, tags
+	kept := make([]ident.Ident, 0, len(t1))
+	var removedH, removedL uint64
+	remove := func(t ident.Ident) {
+		removedH ^= t.HashH()
+		removedL ^= t.HashL()
+	}
 
-	serialization := make([]byte, 0, len(ts1.serialization)+len(ts2.serialization)+1)
-	serialization = append(serialization, ts1.serialization...)
-	if len(serialization) > 0 {
-		serialization = append(serialization, ',')
+	for len(t1) > 0 && len(t2) > 0 {
+		switch compareTags(t1[0], t2[0]) {
+		case 0:
+			if common {
+				kept = append(kept, t1[0])
+			} else {
+				remove(t1[0])
+			}
+			t1, t2 = t1[1:], t2[1:]
+		case 1:
+			t2 = t2[1:]
+		default:
+			if common {
+				remove(t1[0])
+			} else {
+				kept = append(kept, t1[0])
+			}
+			t1 = t1[1:]
+		}
+	}
+	for _, t := range t1 {
+		if common {
+			remove(t)
+		} else {
+			kept = append(kept, t)
+		}
+	}
+
+	return f.subset(ts, kept, removedH, removedL)
+}
+
+// subset creates a TagSet containing the given subset of the tags of ts,
+// where removedH and removedL are the XOR of the hashes of the removed tags.
+// If nothing was removed, ts itself is returned.
+func (f *NullFoundry) subset(ts *TagSet, kept []ident.Ident, removedH, removedL uint64) *TagSet {
+	switch len(kept) {
+	case len(ts.tags):
+		return ts
+	case 0:
+		return emptyTagSet
 	}
-	serialization = append(serialization, ts2.serialization...)
+	return f.newTagSet(kept, ts.hashH^removedH, ts.hashL^removedL)
+}
 
-	return &TagSet{
-		size:          len(clone),
-		tags:          clone,
-		hashH:         ts1.hashH ^ ts2.hashH,
-		hashL:         ts1.hashL ^ ts2.hashL,
-		serialization: serialization,
+// compareTags compares two tags by hash, returning -1, 0, or 1 in the order
+// used by ident.Sort.
+func compareTags(t1, t2 ident.Ident) int {
+	h1, h2 := t1.HashH(), t2.HashH()
+	switch {
+	case h1 < h2:
+		return -1
+	case h1 > h2:
+		return 1
+	}
+	l1, l2 := t1.HashL(), t2.HashL()
+	switch {
+	case l1 < l2:
+		return -1
+	case l1 > l2:
+		return 1
+	}
+	return 0
+}
+
+// dedupKeys removes tags from the slice which have the same key as another
+// tag, keeping the first or last in the slice according to mode.  The slice
+// is modified in-place.
+func dedupKeys(tags []ident.Ident, mode KeyDedupMode) []ident.Ident {
+	// sort indexes into tags by key, retaining their order within each key
+	order := make([]int, len(tags))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return bytes.Compare(tagKey(tags[order[i]].Bytes()), tagKey(tags[order[j]].Bytes())) < 0
+	})
+
+	// mark the tags to drop by setting them to nil
+	for start := 0; start < len(order); {
+		key := tagKey(tags[order[start]].Bytes())
+		end := start + 1
+		for end < len(order) && bytes.Equal(tagKey(tags[order[end]].Bytes()), key) {
+			end++
+		}
+		keep := order[start]
+		if mode == LastKeyWins {
+			keep = order[end-1]
+		}
+		for _, i := range order[start:end] {
+			if i != keep {
+				tags[i] = nil
+			}
+		}
+		start = end
+	}
+
+	kept := tags[:0]
+	for _, t := range tags {
+		if t != nil {
+			kept = append(kept, t)
+		}
 	}
+	return kept
 }
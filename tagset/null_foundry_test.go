@@ -1,19 +1,31 @@
-package tagset
+package tagset_test
 
 import (
 	"testing"
 
+	"github.com/djmitche/tagset/tagset"
+	"github.com/djmitche/tagset/tagset/tagsettest"
 	"github.com/stretchr/testify/suite"
 )
 
 type NullFoundrySuite struct {
-	FoundrySuite
+	tagsettest.FoundrySuite
 }
 
 func TestNullFoundry(t *testing.T) {
 	suite.Run(t, &NullFoundrySuite{
-		FoundrySuite: FoundrySuite{
-			f: NewNullFoundry(),
+		FoundrySuite: tagsettest.FoundrySuite{
+			NewFoundry: func() tagset.Foundry { return tagset.Must(tagset.NewNullFoundry()) },
+		},
+	})
+}
+
+func TestNullFoundryEager(t *testing.T) {
+	suite.Run(t, &NullFoundrySuite{
+		FoundrySuite: tagsettest.FoundrySuite{
+			NewFoundry: func() tagset.Foundry {
+				return tagset.Must(tagset.NewNullFoundry(tagset.WithSerialization(tagset.EagerSerialization)))
+			},
 		},
 	})
 }
@@ -0,0 +1,381 @@
+package tagset
+
+import (
+	"fmt"
+	"time"
+)
+
+// An Option configures a foundry when it is created, and is passed to the
+// foundry's constructor.  Constructors return an error for options that do
+// not apply to that foundry.
+type Option func(*options) error
+
+// An optionID identifies an Option, so that constructors can reject options
+// that do not apply to them.
+type optionID int
+
+const (
+	optCapacity optionID = iota
+	optSerialization
+	optKeyDedup
+	optUnionCacheSize
+	optContentInterning
+	optGenerations
+	optRotateAfter
+	optRotateEvery
+	optMaxEntries
+	optClock
+	optShards
+	optParseOptions
+)
+
+// String returns the name of the function creating the option
+func (id optionID) String() string {
+	switch id {
+	case optCapacity:
+		return "WithCapacity"
+	case optSerialization:
+		return "WithSerialization"
+	case optKeyDedup:
+		return "WithKeyDedup"
+	case optUnionCacheSize:
+		return "WithUnionCacheSize"
+	case optContentInterning:
+		return "WithContentInterning"
+	case optGenerations:
+		return "WithGenerations"
+	case optRotateAfter:
+		return "WithRotateAfter"
+	case optRotateEvery:
+		return "WithRotateEvery"
+	case optMaxEntries:
+		return "WithMaxEntries"
+	case optClock:
+		return "WithClock"
+	case optShards:
+		return "WithShards"
+	case optParseOptions:
+		return "WithParseOptions"
+	default:
+		return fmt.Sprintf("optionID(%d)", int(id))
+	}
+}
+
+// options collects the configuration from a list of Options
+type options struct {
+	// capacity is the number of TagSets for which to pre-size caches
+	capacity int
+
+	// serialization determines when TagSet serializations are computed
+	serialization SerializationMode
+
+	// keyDedup determines how tags with the same key are handled
+	keyDedup KeyDedupMode
+
+	// unionCacheSize bounds the number of cached unions; 0 disables caching
+	unionCacheSize int
+
+	// contentInterning enables interning TagSets by their hash
+	contentInterning bool
+
+	// generations, rotateAfter, rotateEvery, and maxEntries configure
+	// RevolvingFoundry
+	generations int
+	rotateAfter int
+	rotateEvery time.Duration
+	maxEntries  int
+
+	// clock returns the current time
+	clock func() time.Time
+
+	// shards is the number of shards in a ShardedFoundry
+	shards int
+
+	// parse configures Parse
+	parse ParseOptions
+
+	// given contains the options that were given
+	given []optionID
+}
+
+// defaultOptions are used for any options not given to a constructor
+var defaultOptions = options{
+	capacity:       0,
+	serialization:  LazySerialization,
+	keyDedup:       KeepAllKeys,
+	unionCacheSize: 0,
+	generations:    3,
+	rotateAfter:    5000,
+	rotateEvery:    0,
+	maxEntries:     1000000,
+	clock:          time.Now,
+	shards:         16,
+}
+
+// WithUnionCacheSize bounds the number of unions a foundry caches.  The cache
+// holds at least this many unions, and at most twice as many.  A size of 0,
+// the default, disables the cache.
+func WithUnionCacheSize(size int) Option {
+	return func(o *options) error {
+		if size < 0 {
+			return fmt.Errorf("union cache size must not be negative, got %d", size)
+		}
+		o.unionCacheSize = size
+		o.given = append(o.given, optUnionCacheSize)
+		return nil
+	}
+}
+
+// WithContentInterning enables or disables content-addressed interning, in
+// which every TagSet a foundry creates is looked up by its hash, and an
+// existing TagSet with the same tags is returned in its place.  TagSets from
+// such a foundry may be compared by pointer, and each distinct set is
+// serialized at most once.  The default is disabled.
+func WithContentInterning(enabled bool) Option {
+	return func(o *options) error {
+		o.contentInterning = enabled
+		o.given = append(o.given, optContentInterning)
+		return nil
+	}
+}
+
+// WithGenerations sets the number of generations in a RevolvingFoundry.  This
+// must be at least 2.  The default is 3.
+func WithGenerations(generations int) Option {
+	return func(o *options) error {
+		if generations < 2 {
+			return fmt.Errorf("generations must be at least 2, got %d", generations)
+		}
+		o.generations = generations
+		o.given = append(o.given, optGenerations)
+		return nil
+	}
+}
+
+// WithRotateAfter sets the number of parses after which a RevolvingFoundry
+// rotates its generations.  A value of 0 disables count-based rotation.  The
+// default is 5000.
+func WithRotateAfter(rotateAfter int) Option {
+	return func(o *options) error {
+		if rotateAfter < 0 {
+			return fmt.Errorf("rotateAfter must not be negative, got %d", rotateAfter)
+		}
+		o.rotateAfter = rotateAfter
+		o.given = append(o.given, optRotateAfter)
+		return nil
+	}
+}
+
+// WithRotateEvery sets the interval at which a RevolvingFoundry rotates its
+// generations, checked on each parse.  A value of 0 disables time-based
+// rotation, and is the default.
+func WithRotateEvery(interval time.Duration) Option {
+	return func(o *options) error {
+		if interval < 0 {
+			return fmt.Errorf("rotateEvery must not be negative, got %s", interval)
+		}
+		o.rotateEvery = interval
+		o.given = append(o.given, optRotateEvery)
+		return nil
+	}
+}
+
+// WithMaxEntries bounds the number of parse cache entries in a
+// RevolvingFoundry, across all generations.  When the limit is reached, the
+// foundry rotates early.  A value of 0 disables the limit.  The default is
+// 1,000,000.
+func WithMaxEntries(maxEntries int) Option {
+	return func(o *options) error {
+		if maxEntries < 0 {
+			return fmt.Errorf("maxEntries must not be negative, got %d", maxEntries)
+		}
+		o.maxEntries = maxEntries
+		o.given = append(o.given, optMaxEntries)
+		return nil
+	}
+}
+
+// WithClock sets the function a foundry uses to get the current time, for
+// time-based rotation.  This is intended for tests.  The default is
+// time.Now.
+func WithClock(clock func() time.Time) Option {
+	return func(o *options) error {
+		if clock == nil {
+			return fmt.Errorf("clock must not be nil")
+		}
+		o.clock = clock
+		o.given = append(o.given, optClock)
+		return nil
+	}
+}
+
+// WithShards sets the number of shards in a ShardedFoundry's parse cache,
+// rounded up to a power of two.  The default is 16.
+func WithShards(shards int) Option {
+	return func(o *options) error {
+		if shards < 1 {
+			return fmt.Errorf("shards must be at least 1, got %d", shards)
+		}
+		o.shards = shards
+		o.given = append(o.given, optShards)
+		return nil
+	}
+}
+
+// A SerializationMode determines when a foundry computes the serialization
+// of the TagSets it creates.
+type SerializationMode int
+
+const (
+	// LazySerialization computes serializations on the first call to
+	// TagSet.Serialization, so intermediate TagSets that are never
+	// serialized cost nothing.
+	LazySerialization SerializationMode = iota
+
+	// EagerSerialization computes serializations when each TagSet is
+	// created, as is done for hashes.
+	EagerSerialization
+)
+
+// String returns the name of the mode, as used in foundry specs
+func (m SerializationMode) String() string {
+	switch m {
+	case LazySerialization:
+		return "lazy"
+	case EagerSerialization:
+		return "eager"
+	default:
+		return fmt.Sprintf("SerializationMode(%d)", int(m))
+	}
+}
+
+// ParseSerializationMode parses the name of a SerializationMode
+func ParseSerializationMode(name string) (SerializationMode, error) {
+	switch name {
+	case "lazy":
+		return LazySerialization, nil
+	case "eager":
+		return EagerSerialization, nil
+	default:
+		return 0, fmt.Errorf("unknown serialization mode %q", name)
+	}
+}
+
+// applyOptions applies the given options to the defaults, returning the first
+// error encountered.  Options not in `accepted` are rejected.
+func applyOptions(opts []Option, accepted ...optionID) (options, error) {
+	o := defaultOptions
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return o, err
+		}
+	}
+Given:
+	for _, id := range o.given {
+		for _, a := range accepted {
+			if id == a {
+				continue Given
+			}
+		}
+		return o, fmt.Errorf("option %s does not apply to this foundry", id)
+	}
+	return o, nil
+}
+
+// WithCapacity pre-sizes the foundry's caches to hold the given number of
+// TagSets without rehashing.
+func WithCapacity(capacity int) Option {
+	return func(o *options) error {
+		if capacity < 0 {
+			return fmt.Errorf("capacity must not be negative, got %d", capacity)
+		}
+		o.capacity = capacity
+		o.given = append(o.given, optCapacity)
+		return nil
+	}
+}
+
+// A KeyDedupMode determines how a foundry's NewWithDuplicates and Parse
+// methods handle multiple tags with the same key, such as `service:web` and
+// `service:api`.  See TagSet.Get for the definition of a key.
+type KeyDedupMode int
+
+const (
+	// KeepAllKeys keeps all tags, regardless of their keys.
+	KeepAllKeys KeyDedupMode = iota
+
+	// FirstKeyWins keeps only the first tag with each key, in the order
+	// given to NewWithDuplicates or Parse.
+	FirstKeyWins
+
+	// LastKeyWins keeps only the last tag with each key, in the order given
+	// to NewWithDuplicates or Parse.
+	LastKeyWins
+)
+
+// String returns the name of the mode, as used in foundry specs
+func (m KeyDedupMode) String() string {
+	switch m {
+	case KeepAllKeys:
+		return "all"
+	case FirstKeyWins:
+		return "first"
+	case LastKeyWins:
+		return "last"
+	default:
+		return fmt.Sprintf("KeyDedupMode(%d)", int(m))
+	}
+}
+
+// ParseKeyDedupMode parses the name of a KeyDedupMode
+func ParseKeyDedupMode(name string) (KeyDedupMode, error) {
+	switch name {
+	case "all":
+		return KeepAllKeys, nil
+	case "first":
+		return FirstKeyWins, nil
+	case "last":
+		return LastKeyWins, nil
+	default:
+		return 0, fmt.Errorf("unknown key dedup mode %q", name)
+	}
+}
+
+// WithKeyDedup determines how the foundry handles tags with the same key
+// when creating TagSets with NewWithDuplicates or Parse.  The default is
+// KeepAllKeys.
+func WithKeyDedup(mode KeyDedupMode) Option {
+	return func(o *options) error {
+		if mode != KeepAllKeys && mode != FirstKeyWins && mode != LastKeyWins {
+			return fmt.Errorf("invalid key dedup mode %d", int(mode))
+		}
+		o.keyDedup = mode
+		o.given = append(o.given, optKeyDedup)
+		return nil
+	}
+}
+
+// WithSerialization determines whether the foundry computes TagSet
+// serializations eagerly or lazily.  The default is LazySerialization.
+func WithSerialization(mode SerializationMode) Option {
+	return func(o *options) error {
+		if mode != LazySerialization && mode != EagerSerialization {
+			return fmt.Errorf("invalid serialization mode %d", int(mode))
+		}
+		o.serialization = mode
+		o.given = append(o.given, optSerialization)
+		return nil
+	}
+}
+
+// Must is a helper that wraps a call to a foundry constructor, and panics if
+// the error is non-nil.  It is intended for use in variable initializations
+// such as
+//
+//	var tsFoundry = tagset.Must(tagset.NewInternFoundry())
+func Must(f Foundry, err error) Foundry {
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
@@ -0,0 +1,115 @@
+package tagset
+
+import (
+	"testing"
+
+	"github.com/djmitche/tagset/ident"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionValidation(t *testing.T) {
+	_, err := NewInternFoundry(WithCapacity(-1))
+	require.Error(t, err)
+
+	_, err = NewNullFoundry(WithCapacity(-1))
+	require.Error(t, err)
+
+	_, err = NewNullFoundry(WithSerialization(SerializationMode(99)))
+	require.Error(t, err)
+
+	_, err = NewNullFoundry(WithKeyDedup(KeyDedupMode(99)))
+	require.Error(t, err)
+
+	_, err = NewInternFoundry(WithUnionCacheSize(-1))
+	require.Error(t, err)
+
+	_, err = NewArenaFoundry(nil)
+	require.Error(t, err)
+}
+
+func TestOptionsNotApplicable(t *testing.T) {
+	_, err := NewNullFoundry(WithCapacity(100))
+	require.EqualError(t, err, "option WithCapacity does not apply to this foundry")
+
+	_, err = NewInternFoundry(WithShards(4))
+	require.Error(t, err)
+
+	_, err = NewShardedFoundry(WithGenerations(4))
+	require.Error(t, err)
+
+	_, err = NewRevolvingFoundry(WithContentInterning(true))
+	require.Error(t, err)
+
+	_, err = NewShardedFoundry(WithShards(4), WithCapacity(100), WithKeyDedup(LastKeyWins))
+	require.NoError(t, err)
+}
+
+func TestWithCapacity(t *testing.T) {
+	f, err := NewInternFoundry(WithCapacity(1000))
+	require.NoError(t, err)
+	require.Equal(t, 2048, len(f.byParseHash.slots))
+}
+
+func TestWithSerialization(t *testing.T) {
+	tags := func() []ident.Ident {
+		return []ident.Ident{idFoundry.Ident([]byte("a")), idFoundry.Ident([]byte("b"))}
+	}
+
+	lazy, err := NewNullFoundry()
+	require.NoError(t, err)
+	ts := lazy.NewWithoutDuplicates(tags())
+	require.Nil(t, ts.serialization)
+	require.Equal(t, serializationOf("a", "b"), ts.Serialization())
+
+	eager, err := NewInternFoundry(WithSerialization(EagerSerialization))
+	require.NoError(t, err)
+	ts = eager.NewWithoutDuplicates(tags())
+	require.Equal(t, serializationOf("a", "b"), ts.serialization)
+	ts = eager.Union(ts, eager.NewWithoutDuplicates(tags()[:1]))
+	require.Equal(t, serializationOf("a", "b"), ts.serialization)
+}
+
+func TestSerializationModeString(t *testing.T) {
+	for _, mode := range []SerializationMode{LazySerialization, EagerSerialization} {
+		parsed, err := ParseSerializationMode(mode.String())
+		require.NoError(t, err)
+		require.Equal(t, mode, parsed)
+	}
+	_, err := ParseSerializationMode("bogus")
+	require.Error(t, err)
+}
+
+func TestKeyDedupModeString(t *testing.T) {
+	for _, mode := range []KeyDedupMode{KeepAllKeys, FirstKeyWins, LastKeyWins} {
+		parsed, err := ParseKeyDedupMode(mode.String())
+		require.NoError(t, err)
+		require.Equal(t, mode, parsed)
+	}
+	_, err := ParseKeyDedupMode("bogus")
+	require.Error(t, err)
+}
+
+func TestWithKeyDedup(t *testing.T) {
+	parse := func(mode KeyDedupMode, raw string) []string {
+		f, err := NewInternFoundry(WithKeyDedup(mode))
+		require.NoError(t, err)
+		return f.Parse(idFoundry, []byte(raw)).Strings()
+	}
+
+	raw := "service:web,env:prod,service:api,bare,env:prod,service:db,bare"
+	require.ElementsMatch(t,
+		[]string{"service:web", "env:prod", "service:api", "bare", "service:db"},
+		parse(KeepAllKeys, raw))
+	require.ElementsMatch(t,
+		[]string{"service:web", "env:prod", "bare"},
+		parse(FirstKeyWins, raw))
+	require.ElementsMatch(t,
+		[]string{"env:prod", "service:db", "bare"},
+		parse(LastKeyWins, raw))
+	require.ElementsMatch(t, []string{"a:1"}, parse(LastKeyWins, "a:1"))
+}
+
+func TestMust(t *testing.T) {
+	require.NotNil(t, Must(NewInternFoundry()))
+	require.Panics(t, func() { Must(NewInternFoundry(WithCapacity(-1))) })
+}
@@ -0,0 +1,202 @@
+package tagset
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/djmitche/tagset/ident"
+)
+
+// A ParseProblemKind identifies a kind of problem found by ParseChecked.
+type ParseProblemKind int
+
+const (
+	// The input has more than ParseOptions.MaxTags tags.  Tags with other
+	// problems count toward the limit, as they do for Parse.
+	TooManyTags ParseProblemKind = iota
+
+	// The input is longer than ParseOptions.MaxLength bytes.
+	InputTooLong
+
+	// A tag is longer than ParseOptions.MaxTagLength bytes.
+	TagTooLong
+
+	// A tag is not valid UTF-8.
+	InvalidUTF8
+
+	// A tag is empty.  Empty tags are not reported if ParseOptions.SkipEmpty
+	// is set.
+	EmptyTag
+)
+
+// String returns a short description of the kind of problem.
+func (k ParseProblemKind) String() string {
+	switch k {
+	case TooManyTags:
+		return "too many tags"
+	case InputTooLong:
+		return "input too long"
+	case TagTooLong:
+		return "tag too long"
+	case InvalidUTF8:
+		return "invalid UTF-8"
+	case EmptyTag:
+		return "empty tag"
+	default:
+		return fmt.Sprintf("ParseProblemKind(%d)", int(k))
+	}
+}
+
+// A ParseProblem describes a single problem found by ParseChecked.
+type ParseProblem struct {
+	Kind ParseProblemKind
+
+	// Index is the position of the tag in the input, counting from zero, or
+	// -1 for problems with the input as a whole.
+	Index int
+
+	// Length is the length, in bytes, of the tag or the input.
+	Length int
+}
+
+func (p ParseProblem) String() string {
+	if p.Index < 0 {
+		return fmt.Sprintf("%s (%d bytes)", p.Kind, p.Length)
+	}
+	return fmt.Sprintf("tag %d: %s (%d bytes)", p.Index, p.Kind, p.Length)
+}
+
+// A ParseError is returned from ParseChecked when the input has problems.
+type ParseError struct {
+	// Problems found in the input, in the order they occur.  Once MaxTags is
+	// reached, the remainder of the input is not examined, nor are the tags of
+	// an input rejected for exceeding MaxLength.
+	Problems []ParseProblem
+
+	// Truncated is true if ParseOptions.Truncate was set, in which case
+	// ParseChecked also returned a TagSet without the problematic tags.
+	Truncated bool
+}
+
+func (e *ParseError) Error() string {
+	msg := e.Problems[0].String()
+	if len(e.Problems) > 1 {
+		msg = fmt.Sprintf("%s (and %d more problems)", msg, len(e.Problems)-1)
+	}
+	if e.Truncated {
+		return "tags truncated: " + msg
+	}
+	return "tags rejected: " + msg
+}
+
+// ParseChecked builds the TagSet from the tags split while checking, so the
+// input is scanned only once.
+func (f *NullFoundry) ParseChecked(foundry ident.Foundry, rawTags []byte) (*TagSet, error) {
+	kept, perr := f.checkParse(rawTags)
+	if perr != nil && !perr.Truncated {
+		return nil, perr
+	}
+	ts := f.NewWithDuplicates(identsOf(foundry, kept))
+	if perr != nil {
+		return ts, perr
+	}
+	return ts, nil
+}
+
+// A parseCache is a Foundry that caches the results of Parse by input.
+type parseCache interface {
+	Foundry
+
+	// cachedParse returns the cached TagSet for the input, or calls `miss`
+	// to create one and caches that.
+	cachedParse(rawTags []byte, miss func() *TagSet) *TagSet
+}
+
+// parseChecked implements ParseChecked for foundries embedding a NullFoundry
+// that cache parses.  Input without problems is looked up in the cache, and
+// on a miss the TagSet is built from the tags split while checking, so the
+// input is scanned only once.  Truncated input is not cached, and uses the
+// outer foundry's NewWithDuplicates.
+func (f *NullFoundry) parseChecked(outer parseCache, foundry ident.Foundry, rawTags []byte) (*TagSet, error) {
+	kept, perr := f.checkParse(rawTags)
+	if perr == nil {
+		return outer.cachedParse(rawTags, func() *TagSet {
+			return f.NewWithDuplicates(identsOf(foundry, kept))
+		}), nil
+	}
+	if !perr.Truncated {
+		return nil, perr
+	}
+	return outer.NewWithDuplicates(identsOf(foundry, kept)), perr
+}
+
+// identsOf converts tags to Idents
+func identsOf(foundry ident.Foundry, tags [][]byte) []ident.Ident {
+	idents := make([]ident.Ident, len(tags))
+	for i, tag := range tags {
+		idents[i] = foundry.Ident(tag)
+	}
+	return idents
+}
+
+// checkParse splits the input as Parse does, checking it for problems.  It
+// returns the tags without problems, which refer to the input buffer, and a
+// ParseError if there are any problems.
+func (f *NullFoundry) checkParse(rawTags []byte) ([][]byte, *ParseError) {
+	s := f.splitter
+	if s == nil {
+		s = defaultSplitter
+	}
+
+	var problems []ParseProblem
+	if s.MaxLength > 0 && len(rawTags) > s.MaxLength {
+		problems = append(problems, ParseProblem{InputTooLong, -1, len(rawTags)})
+		if !s.Truncate {
+			return nil, &ParseError{Problems: problems}
+		}
+
+		// cut the input at the last separator within the limit, so that no
+		// partial tag remains
+		cut := s.MaxLength
+		if !s.isSeparator[rawTags[cut]] {
+			for cut > 0 && !s.isSeparator[rawTags[cut-1]] {
+				cut--
+			}
+			if cut > 0 {
+				cut--
+			}
+		}
+		rawTags = rawTags[:cut]
+	}
+
+	var kept [][]byte
+	index := 0
+	extra, tooMany := s.split(rawTags, func(tag []byte) {
+		problem := ParseProblem{Index: index, Length: len(tag)}
+		index++
+		switch {
+		case len(tag) == 0:
+			problem.Kind = EmptyTag
+		case s.MaxTagLength > 0 && len(tag) > s.MaxTagLength:
+			problem.Kind = TagTooLong
+		case !utf8.Valid(tag):
+			problem.Kind = InvalidUTF8
+		default:
+			kept = append(kept, tag)
+			return
+		}
+		problems = append(problems, problem)
+	})
+	if tooMany {
+		// the remainder of the input is not examined
+		problems = append(problems, ParseProblem{TooManyTags, index, len(extra)})
+	}
+
+	if len(problems) == 0 {
+		return kept, nil
+	}
+	if !s.Truncate {
+		return nil, &ParseError{Problems: problems}
+	}
+	return kept, &ParseError{Problems: problems, Truncated: true}
+}
@@ -0,0 +1,174 @@
+package tagset
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func parseChecked(t *testing.T, po ParseOptions, raw string) ([]string, *ParseError) {
+	f, err := NewNullFoundry(WithParseOptions(po))
+	require.NoError(t, err)
+	ts, err := f.ParseChecked(idFoundry, []byte(raw))
+	if err == nil {
+		return ts.Strings(), nil
+	}
+	var perr *ParseError
+	require.True(t, errors.As(err, &perr))
+	if perr.Truncated {
+		require.NotNil(t, ts)
+		return ts.Strings(), perr
+	}
+	require.Nil(t, ts)
+	return nil, perr
+}
+
+func TestParseCheckedValid(t *testing.T) {
+	tags, perr := parseChecked(t, ParseOptions{MaxTags: 3, MaxLength: 11, MaxTagLength: 3},
+		"a:1,b:2,c:3")
+	require.Nil(t, perr)
+	require.ElementsMatch(t, []string{"a:1", "b:2", "c:3"}, tags)
+
+	tags, perr = parseChecked(t, ParseOptions{SkipEmpty: true, TrimSpace: true}, "a, ,b,,")
+	require.Nil(t, perr)
+	require.ElementsMatch(t, []string{"a", "b"}, tags)
+
+	tags, perr = parseChecked(t, ParseOptions{}, "café,日本")
+	require.Nil(t, perr)
+	require.ElementsMatch(t, []string{"café", "日本"}, tags)
+}
+
+func TestParseCheckedReject(t *testing.T) {
+	_, perr := parseChecked(t, ParseOptions{MaxTags: 2}, "a,b,c,d")
+	require.Equal(t, []ParseProblem{{TooManyTags, 2, 1}}, perr.Problems)
+
+	_, perr = parseChecked(t, ParseOptions{MaxLength: 5}, "a,b,c,d")
+	require.Equal(t, []ParseProblem{{InputTooLong, -1, 7}}, perr.Problems)
+
+	_, perr = parseChecked(t, ParseOptions{MaxTagLength: 3}, "abc,abcd,ab")
+	require.Equal(t, []ParseProblem{{TagTooLong, 1, 4}}, perr.Problems)
+
+	_, perr = parseChecked(t, ParseOptions{}, "a,\xc3\x28,b")
+	require.Equal(t, []ParseProblem{{InvalidUTF8, 1, 2}}, perr.Problems)
+
+	// problems after MaxTags tags are not examined
+	_, perr = parseChecked(t, ParseOptions{MaxTags: 2}, "a,b,,\xff,c")
+	require.Equal(t, []ParseProblem{{TooManyTags, 2, 0}}, perr.Problems)
+
+	_, perr = parseChecked(t, ParseOptions{}, "a,,b,")
+	require.Equal(t, []ParseProblem{{EmptyTag, 1, 0}, {EmptyTag, 3, 0}}, perr.Problems)
+	require.Equal(t, "tags rejected: tag 1: empty tag (0 bytes) (and 1 more problems)", perr.Error())
+}
+
+func TestParseCheckedTruncate(t *testing.T) {
+	tags, perr := parseChecked(t, ParseOptions{MaxTags: 2, Truncate: true}, "a,b,c,d")
+	require.Equal(t, []ParseProblem{{TooManyTags, 2, 1}}, perr.Problems)
+	require.ElementsMatch(t, []string{"a", "b"}, tags)
+
+	// problematic tags count toward MaxTags, as they do for Parse
+	tags, perr = parseChecked(t, ParseOptions{MaxTags: 2, Truncate: true}, "a,,b,c")
+	require.Equal(t, []ParseProblem{{EmptyTag, 1, 0}, {TooManyTags, 2, 1}}, perr.Problems)
+	require.ElementsMatch(t, []string{"a"}, tags)
+
+	tags, perr = parseChecked(t, ParseOptions{MaxTags: 2, Truncate: true}, "a,b,,\xff")
+	require.Equal(t, []ParseProblem{{TooManyTags, 2, 0}}, perr.Problems)
+	require.ElementsMatch(t, []string{"a", "b"}, tags)
+
+	tags, perr = parseChecked(t, ParseOptions{MaxTagLength: 3, Truncate: true}, "abc,abcd,ab,\xff")
+	require.Equal(t, []ParseProblem{{TagTooLong, 1, 4}, {InvalidUTF8, 3, 1}}, perr.Problems)
+	require.ElementsMatch(t, []string{"abc", "ab"}, tags)
+	require.Equal(t, "tags truncated: tag 1: tag too long (4 bytes) (and 1 more problems)", perr.Error())
+
+	tags, perr = parseChecked(t, ParseOptions{Truncate: true}, ",,")
+	require.Len(t, perr.Problems, 3)
+	require.Empty(t, tags)
+}
+
+func TestParseCheckedTruncateLength(t *testing.T) {
+	po := ParseOptions{MaxLength: 8, Truncate: true}
+
+	// the limit falls within a tag, which is dropped
+	tags, perr := parseChecked(t, po, "abc,defgh,ij")
+	require.Equal(t, []ParseProblem{{InputTooLong, -1, 12}}, perr.Problems)
+	require.ElementsMatch(t, []string{"abc"}, tags)
+
+	// the limit falls on a separator
+	tags, _ = parseChecked(t, po, "abc,defg,ij")
+	require.ElementsMatch(t, []string{"abc", "defg"}, tags)
+
+	// the limit falls just after a separator
+	tags, _ = parseChecked(t, po, "abcdefg,ij")
+	require.ElementsMatch(t, []string{"abcdefg"}, tags)
+
+	// the first tag exceeds the limit
+	tags, _ = parseChecked(t, po, strings.Repeat("x", 20))
+	require.Empty(t, tags)
+}
+
+func TestParseCheckedZeroFoundry(t *testing.T) {
+	var f NullFoundry
+	_, err := f.ParseChecked(idFoundry, []byte("a,"))
+	require.Error(t, err)
+	ts, err := f.ParseChecked(idFoundry, []byte("a,b"))
+	require.NoError(t, err)
+	require.Equal(t, 2, ts.Len())
+}
+
+func TestParseCheckedAgreesWithParse(t *testing.T) {
+	for _, po := range []ParseOptions{
+		{MaxTags: 2, Truncate: true},
+		{MaxTags: 2, SkipEmpty: true, Truncate: true},
+		{MaxTags: 3, Separators: []byte(", "), TrimSpace: true, Truncate: true},
+	} {
+		f, err := NewNullFoundry(WithParseOptions(po))
+		require.NoError(t, err)
+		for _, raw := range []string{"a,a,b", "a,b", ",a,,b,c", "a, b c,d", "a,b,c,d"} {
+			parsed := f.Parse(idFoundry, []byte(raw))
+			checked, err := f.ParseChecked(idFoundry, []byte(raw))
+
+			// the same tags are counted toward MaxTags, so where ParseChecked
+			// finds no problems other than too many tags, the results agree
+			var perr *ParseError
+			if err == nil || (errors.As(err, &perr) && perr.Problems[0].Kind == TooManyTags) {
+				require.ElementsMatch(t, parsed.Strings(), checked.Strings(), "%v %q", po, raw)
+			}
+		}
+	}
+}
+
+func TestParseCheckedInterns(t *testing.T) {
+	f, err := NewInternFoundry(WithParseOptions(ParseOptions{MaxTags: 2, Truncate: true}))
+	require.NoError(t, err)
+
+	ts1, err := f.ParseChecked(idFoundry, []byte("a,b"))
+	require.NoError(t, err)
+	require.True(t, ts1 == f.Parse(idFoundry, []byte("a,b")))
+	again, err := f.ParseChecked(idFoundry, []byte("a,b"))
+	require.NoError(t, err)
+	require.True(t, ts1 == again)
+	require.Equal(t, uint64(3), f.Parses)
+	require.Equal(t, uint64(1), f.ParseMisses)
+
+	ts2, err := f.ParseChecked(idFoundry, []byte("a,b,c"))
+	require.Error(t, err)
+	require.ElementsMatch(t, []string{"a", "b"}, ts2.Strings())
+}
+
+func TestParseCheckedFromParams(t *testing.T) {
+	f, err := NewFromSpec("intern{maxLength:5,maxTagLength:2,truncate:true}")
+	require.NoError(t, err)
+	ts, err := f.ParseChecked(idFoundry, []byte("a,bcd,e,f"))
+	require.Error(t, err)
+	require.ElementsMatch(t, []string{"a"}, ts.Strings())
+
+	_, err = NewFromSpec("null{maxLength:-1}")
+	require.Error(t, err)
+}
+
+func TestParseProblemKindString(t *testing.T) {
+	require.Equal(t, "invalid UTF-8", InvalidUTF8.String())
+	require.Equal(t, "ParseProblemKind(99)", ParseProblemKind(99).String())
+	require.Equal(t, "input too long (10 bytes)", ParseProblem{InputTooLong, -1, 10}.String())
+}
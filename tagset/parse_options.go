@@ -0,0 +1,170 @@
+package tagset
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ParseOptions configure how a foundry's Parse method splits its input into
+// tags.  The zero value splits on commas and keeps every tag, including empty
+// tags, as Parse does by default.
+type ParseOptions struct {
+	// Separators is the set of bytes that separate tags.  If empty, tags are
+	// separated by commas.
+	Separators []byte
+
+	// TrimSpace removes leading and trailing whitespace from each tag.
+	TrimSpace bool
+
+	// SkipEmpty ignores empty tags, such as in `a,,b` or a trailing comma.
+	// With TrimSpace, tags containing only whitespace are also ignored.
+	SkipEmpty bool
+
+	// StripHash removes a single leading `#` from the input, as found in
+	// DogStatsD tag fields such as `#a,b`.
+	StripHash bool
+
+	// MaxTags limits the number of tags parsed from the input; any further
+	// tags are ignored, or reported by ParseChecked.  Every tag counts toward
+	// the limit, including duplicates and, for ParseChecked, tags with other
+	// problems; only tags ignored by SkipEmpty do not.  A value of 0 means no
+	// limit.
+	MaxTags int
+
+	// MaxLength limits the length of the input, in bytes, and MaxTagLength
+	// limits the length of each tag.  A value of 0 means no limit.  These
+	// limits are applied only by ParseChecked.
+	MaxLength, MaxTagLength int
+
+	// Truncate causes ParseChecked to drop the parts of the input that have
+	// problems and return a TagSet of the remaining tags, rather than
+	// rejecting the input.
+	Truncate bool
+}
+
+// WithParseOptions configures the foundry's Parse method.  See ParseOptions.
+func WithParseOptions(parseOpts ParseOptions) Option {
+	return func(o *options) error {
+		if parseOpts.MaxTags < 0 {
+			return fmt.Errorf("MaxTags must not be negative, got %d", parseOpts.MaxTags)
+		}
+		if parseOpts.MaxLength < 0 {
+			return fmt.Errorf("MaxLength must not be negative, got %d", parseOpts.MaxLength)
+		}
+		if parseOpts.MaxTagLength < 0 {
+			return fmt.Errorf("MaxTagLength must not be negative, got %d", parseOpts.MaxTagLength)
+		}
+		o.parse = parseOpts
+		o.given = append(o.given, optParseOptions)
+		return nil
+	}
+}
+
+// isDefault returns true if these options produce the default behavior of
+// Parse, which has a faster implementation.  Options used only by
+// ParseChecked are not considered.
+func (po *ParseOptions) isDefault() bool {
+	return !po.TrimSpace && !po.SkipEmpty && !po.StripHash && po.MaxTags == 0 &&
+		(len(po.Separators) == 0 || bytes.Equal(po.Separators, commaSeparator))
+}
+
+// A tagSplitter splits raw input into tags, according to ParseOptions.
+type tagSplitter struct {
+	ParseOptions
+
+	// isSeparator[b] is true if b is a separator
+	isSeparator [256]bool
+
+	// simple is true if split can use its faster comma-only implementation
+	simple bool
+}
+
+// defaultSplitter is used by foundries without ParseOptions
+var defaultSplitter = newTagSplitter(ParseOptions{})
+
+func newTagSplitter(po ParseOptions) *tagSplitter {
+	s := &tagSplitter{ParseOptions: po, simple: po.isDefault()}
+	separators := po.Separators
+	if len(separators) == 0 {
+		separators = commaSeparator
+	}
+	for _, b := range separators {
+		s.isSeparator[b] = true
+	}
+	return s
+}
+
+// forEach calls the given function for each tag in the input, after trimming
+// and skipping as configured, until it returns false.  MaxTags is not
+// applied.
+func (s *tagSplitter) forEach(rawTags []byte, f func(tag []byte) bool) {
+	if s.StripHash && len(rawTags) > 0 && rawTags[0] == '#' {
+		rawTags = rawTags[1:]
+	}
+	if len(rawTags) == 0 {
+		return
+	}
+
+	start := 0
+	for i := 0; i <= len(rawTags); i++ {
+		if i < len(rawTags) && !s.isSeparator[rawTags[i]] {
+			continue
+		}
+
+		tag := rawTags[start:i]
+		start = i + 1
+		if s.TrimSpace {
+			tag = bytes.TrimSpace(tag)
+		}
+		if s.SkipEmpty && len(tag) == 0 {
+			continue
+		}
+		if !f(tag) {
+			return
+		}
+	}
+}
+
+// split calls the given function for each tag in the input, as Parse splits
+// it: after trimming and skipping as configured, and up to MaxTags tags.  If
+// the input has more tags than that, split returns true and the first tag
+// beyond the limit.  This is shared by all foundries' Parse and ParseChecked
+// methods, so that they agree on which tags are parsed.
+func (s *tagSplitter) split(rawTags []byte, f func(tag []byte)) (extra []byte, tooMany bool) {
+	if s.simple {
+		if len(rawTags) == 0 {
+			return nil, false
+		}
+		for {
+			tagPos := bytes.IndexByte(rawTags, ',')
+			if tagPos < 0 {
+				break
+			}
+			f(rawTags[:tagPos])
+			rawTags = rawTags[tagPos+1:]
+		}
+		f(rawTags)
+		return nil, false
+	}
+
+	count := 0
+	s.forEach(rawTags, func(tag []byte) bool {
+		if s.MaxTags > 0 && count >= s.MaxTags {
+			extra, tooMany = tag, true
+			return false
+		}
+		count++
+		f(tag)
+		return true
+	})
+	return extra, tooMany
+}
+
+// sizeHint estimates the number of tags split will find in the input, for
+// use as the capacity of a slice of tags.
+func (s *tagSplitter) sizeHint(rawTags []byte) int {
+	if s.simple {
+		return bytes.Count(rawTags, commaSeparator) + 1
+	}
+	return 0
+}
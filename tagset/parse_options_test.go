@@ -0,0 +1,113 @@
+package tagset
+
+import (
+	"testing"
+
+	"github.com/djmitche/tagset/ident"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOptions(t *testing.T) {
+	parse := func(po ParseOptions, raw string) []string {
+		f, err := NewNullFoundry(WithParseOptions(po))
+		require.NoError(t, err)
+		return f.Parse(idFoundry, []byte(raw)).Strings()
+	}
+
+	// the zero value parses as the default does
+	require.ElementsMatch(t, []string{"a", "", "b"}, parse(ParseOptions{}, "a,,b,"))
+	require.ElementsMatch(t, []string{"#a", " b"}, parse(ParseOptions{}, "#a, b"))
+
+	require.ElementsMatch(t, []string{"a", "b", "c"},
+		parse(ParseOptions{SkipEmpty: true}, ",a,,b,c,"))
+	require.Empty(t, parse(ParseOptions{SkipEmpty: true}, ",,,"))
+
+	require.ElementsMatch(t, []string{"a", "b:c", ""},
+		parse(ParseOptions{TrimSpace: true}, " a ,\tb:c, "))
+	require.ElementsMatch(t, []string{"a", "b:c"},
+		parse(ParseOptions{TrimSpace: true, SkipEmpty: true}, " a ,\tb:c, "))
+
+	require.ElementsMatch(t, []string{"a", "b", "c"},
+		parse(ParseOptions{Separators: []byte(" ;")}, "a b;c"))
+	require.ElementsMatch(t, []string{"a,b"},
+		parse(ParseOptions{Separators: []byte(";")}, "a,b"))
+
+	require.ElementsMatch(t, []string{"a", "b"}, parse(ParseOptions{StripHash: true}, "#a,b"))
+	require.ElementsMatch(t, []string{"#a", "b"}, parse(ParseOptions{StripHash: true}, "##a,b"))
+	require.ElementsMatch(t, []string{"a", "#b"}, parse(ParseOptions{StripHash: true}, "a,#b"))
+	require.Empty(t, parse(ParseOptions{StripHash: true}, "#"))
+
+	require.ElementsMatch(t, []string{"a", "b"}, parse(ParseOptions{MaxTags: 2}, "a,b,c,d"))
+	require.ElementsMatch(t, []string{"a", "b"},
+		parse(ParseOptions{MaxTags: 2, SkipEmpty: true}, ",a,,b,c"))
+	// duplicates count toward the limit
+	require.ElementsMatch(t, []string{"a"}, parse(ParseOptions{MaxTags: 2}, "a,a,b"))
+}
+
+func TestParseOptionsValidation(t *testing.T) {
+	_, err := NewNullFoundry(WithParseOptions(ParseOptions{MaxTags: -1}))
+	require.Error(t, err)
+	_, err = NewNullFoundry(WithParseOptions(ParseOptions{MaxLength: -1}))
+	require.Error(t, err)
+	_, err = NewNullFoundry(WithParseOptions(ParseOptions{MaxTagLength: -1}))
+	require.Error(t, err)
+}
+
+func TestParseOptionsDefault(t *testing.T) {
+	f, err := NewNullFoundry(WithParseOptions(ParseOptions{Separators: []byte(",")}))
+	require.NoError(t, err)
+	require.True(t, f.splitter.simple)
+
+	// limits used only by ParseChecked do not affect Parse
+	f, err = NewNullFoundry(WithParseOptions(ParseOptions{MaxLength: 10, Truncate: true}))
+	require.NoError(t, err)
+	require.True(t, f.splitter.simple)
+
+	f, err = NewNullFoundry(WithParseOptions(ParseOptions{SkipEmpty: true}))
+	require.NoError(t, err)
+	require.False(t, f.splitter.simple)
+}
+
+func TestParseOptionsFoundries(t *testing.T) {
+	po := WithParseOptions(ParseOptions{StripHash: true, TrimSpace: true, SkipEmpty: true})
+	foundries := map[string]Foundry{
+		"intern":    Must(NewInternFoundry(po)),
+		"revolving": Must(NewRevolvingFoundry(po)),
+		"sharded":   Must(NewShardedFoundry(po)),
+	}
+	for name, f := range foundries {
+		t.Run(name, func(t *testing.T) {
+			ts := f.Parse(idFoundry, []byte("#a, b,,"))
+			require.ElementsMatch(t, []string{"a", "b"}, ts.Strings())
+			require.True(t, ts == f.Parse(idFoundry, []byte("#a, b,,")))
+		})
+	}
+}
+
+func TestParseOptionsFromParams(t *testing.T) {
+	f, err := New("null", ident.Params{
+		"separators": ";",
+		"trimSpace":  true,
+		"skipEmpty":  "true",
+		"stripHash":  true,
+		"maxTags":    2,
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"a", "b"},
+		f.Parse(idFoundry, []byte("#a; ;b;c")).Strings())
+
+	f, err = NewFromSpec(`null{separators:", ",skipEmpty:true}`)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"a", "b", "c"}, f.Parse(idFoundry, []byte("a, b,c")).Strings())
+
+	f, err = NewFromSpec("intern{skipEmpty:true,maxTags:1}")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"a"}, f.Parse(idFoundry, []byte(",a,b")).Strings())
+
+	_, err = NewFromSpec("sharded{maxTags:-1}")
+	require.Error(t, err)
+	_, err = NewFromSpec("null{trimSpace:sometimes}")
+	require.Error(t, err)
+	_, err = NewFromSpec("threadsafe{skipEmpty:true}")
+	require.Error(t, err)
+}
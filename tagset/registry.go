@@ -0,0 +1,272 @@
+package tagset
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/djmitche/tagset/ident"
+)
+
+// A Factory creates a foundry from a set of parameters.  Factories should
+// return an error for any parameters they do not recognize.
+type Factory func(params ident.Params) (Foundry, error)
+
+var registry = struct {
+	sync.Mutex
+	factories map[string]Factory
+}{factories: map[string]Factory{}}
+
+// Register makes a foundry available by name, for use with New and
+// NewFromSpec.  It is typically called from an `init` function.  Register
+// panics if the name is already registered, or the factory is nil.
+func Register(name string, factory Factory) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if factory == nil {
+		panic("tagset: Register factory is nil")
+	}
+	if _, dup := registry.factories[name]; dup {
+		panic("tagset: Register called twice for foundry " + name)
+	}
+	registry.factories[name] = factory
+}
+
+// Registered returns the sorted names of all registered foundries.
+func Registered() []string {
+	registry.Lock()
+	defer registry.Unlock()
+
+	names := make([]string, 0, len(registry.factories))
+	for name := range registry.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates a foundry using the factory registered with the given name.
+func New(name string, params ident.Params) (Foundry, error) {
+	registry.Lock()
+	factory, found := registry.factories[name]
+	registry.Unlock()
+
+	if !found {
+		return nil, fmt.Errorf("unknown tagset foundry %q", name)
+	}
+	if params == nil {
+		params = ident.Params{}
+	}
+
+	f, err := factory(params)
+	if err != nil {
+		return nil, fmt.Errorf("creating tagset foundry %q: %w", name, err)
+	}
+	return f, nil
+}
+
+// NewFromSpec creates a foundry from a spec such as `intern{capacity:1000}`.
+// See ident.ParseSpec.
+func NewFromSpec(spec string) (Foundry, error) {
+	name, params, err := ident.ParseSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	return New(name, params)
+}
+
+// The built-in foundries are registered with the following names and
+// parameters:
+//
+//	null{serialization, keyDedup}
+//	intern{capacity, unionCacheSize, contentInterning, serialization, keyDedup}
+//	revolving{size, rotateAfter, rotateEvery, maxEntries, capacity,
+//	  serialization, keyDedup}, where size is the number of generations and
+//	  rotateEvery is a duration with a unit, such as `10m`
+//	threadsafe{inner}, where inner defaults to `intern`
+//	sharded{shards, capacity, serialization, keyDedup}
+//
+// where serialization is `lazy` or `eager`, and keyDedup is `all`, `first`,
+// or `last`.  All but threadsafe also accept the ParseOptions parameters
+// separators (a string of separator bytes), trimSpace, skipEmpty, stripHash,
+// maxTags, maxLength, maxTagLength, and truncate.  In a spec, a separators
+// value containing commas, braces, or whitespace must be quoted, such as
+// `null{separators:", "}`.
+func init() {
+	Register("null", newNullFoundryFromParams)
+	Register("intern", newInternFoundryFromParams)
+	Register("revolving", newRevolvingFoundryFromParams)
+	Register("threadsafe", newThreadsafeFoundryFromParams)
+	Register("sharded", newShardedFoundryFromParams)
+}
+
+// parseParams are the parameters that configure ParseOptions
+var parseParams = []string{"separators", "trimSpace", "skipEmpty", "stripHash", "maxTags",
+	"maxLength", "maxTagLength", "truncate"}
+
+// checkParams checks that params contain only the given keys and parseParams.
+func checkParams(params ident.Params, allowed ...string) error {
+	return params.Check(append(allowed, parseParams...)...)
+}
+
+// intOption returns an IntParam that appends the Option for the parameter's
+// value to opts
+func intOption(opts *[]Option, key string, opt func(int) Option) ident.IntParam {
+	return ident.IntParam{Key: key, Apply: func(v int) { *opts = append(*opts, opt(v)) }}
+}
+
+// boolOption returns a BoolParam that appends the Option for the parameter's
+// value to opts
+func boolOption(opts *[]Option, key string, opt func(bool) Option) ident.BoolParam {
+	return ident.BoolParam{Key: key, Apply: func(v bool) { *opts = append(*opts, opt(v)) }}
+}
+
+// intField returns an IntParam that stores the parameter's value in field
+func intField(key string, field *int) ident.IntParam {
+	return ident.IntParam{Key: key, Apply: func(v int) { *field = v }}
+}
+
+// boolField returns a BoolParam that stores the parameter's value in field
+func boolField(key string, field *bool) ident.BoolParam {
+	return ident.BoolParam{Key: key, Apply: func(v bool) { *field = v }}
+}
+
+// commonOptions converts the optional `capacity`, `serialization`, and
+// `keyDedup` parameters, and the parseParams, to Options.
+func commonOptions(params ident.Params) ([]Option, error) {
+	var opts []Option
+	if err := params.ApplyInts(intOption(&opts, "capacity", WithCapacity)); err != nil {
+		return nil, err
+	}
+	if params.Has("serialization") {
+		name, err := params.String("serialization", "")
+		if err != nil {
+			return nil, err
+		}
+		mode, err := ParseSerializationMode(name)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithSerialization(mode))
+	}
+	if params.Has("keyDedup") {
+		name, err := params.String("keyDedup", "")
+		if err != nil {
+			return nil, err
+		}
+		mode, err := ParseKeyDedupMode(name)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithKeyDedup(mode))
+	}
+
+	var parseOpts ParseOptions
+	separators, err := params.String("separators", "")
+	if err != nil {
+		return nil, err
+	}
+	parseOpts.Separators = []byte(separators)
+	if err := params.ApplyBools(
+		boolField("trimSpace", &parseOpts.TrimSpace),
+		boolField("skipEmpty", &parseOpts.SkipEmpty),
+		boolField("stripHash", &parseOpts.StripHash),
+		boolField("truncate", &parseOpts.Truncate),
+	); err != nil {
+		return nil, err
+	}
+	if err := params.ApplyInts(
+		intField("maxTags", &parseOpts.MaxTags),
+		intField("maxLength", &parseOpts.MaxLength),
+		intField("maxTagLength", &parseOpts.MaxTagLength),
+	); err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithParseOptions(parseOpts))
+
+	return opts, nil
+}
+
+func newNullFoundryFromParams(params ident.Params) (Foundry, error) {
+	if err := checkParams(params, "serialization", "keyDedup"); err != nil {
+		return nil, err
+	}
+	opts, err := commonOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	return NewNullFoundry(opts...)
+}
+
+func newInternFoundryFromParams(params ident.Params) (Foundry, error) {
+	if err := checkParams(params, "capacity", "unionCacheSize", "contentInterning", "serialization", "keyDedup"); err != nil {
+		return nil, err
+	}
+	opts, err := commonOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := params.ApplyInts(intOption(&opts, "unionCacheSize", WithUnionCacheSize)); err != nil {
+		return nil, err
+	}
+	if err := params.ApplyBools(boolOption(&opts, "contentInterning", WithContentInterning)); err != nil {
+		return nil, err
+	}
+	return NewInternFoundry(opts...)
+}
+
+func newRevolvingFoundryFromParams(params ident.Params) (Foundry, error) {
+	if err := checkParams(params, "size", "rotateAfter", "rotateEvery", "maxEntries",
+		"capacity", "serialization", "keyDedup"); err != nil {
+		return nil, err
+	}
+	opts, err := commonOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := params.ApplyInts(
+		intOption(&opts, "size", WithGenerations),
+		intOption(&opts, "rotateAfter", WithRotateAfter),
+		intOption(&opts, "maxEntries", WithMaxEntries),
+	); err != nil {
+		return nil, err
+	}
+	if params.Has("rotateEvery") {
+		interval, err := params.Duration("rotateEvery", 0)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithRotateEvery(interval))
+	}
+	return NewRevolvingFoundry(opts...)
+}
+
+func newThreadsafeFoundryFromParams(params ident.Params) (Foundry, error) {
+	if err := params.Check("inner"); err != nil {
+		return nil, err
+	}
+	innerName, innerParams, err := params.Spec("inner", "intern")
+	if err != nil {
+		return nil, err
+	}
+	inner, err := New(innerName, innerParams)
+	if err != nil {
+		return nil, err
+	}
+	return NewThreadsafeFoundry(inner)
+}
+
+func newShardedFoundryFromParams(params ident.Params) (Foundry, error) {
+	if err := checkParams(params, "shards", "capacity", "serialization", "keyDedup"); err != nil {
+		return nil, err
+	}
+	opts, err := commonOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := params.ApplyInts(intOption(&opts, "shards", WithShards)); err != nil {
+		return nil, err
+	}
+	return NewShardedFoundry(opts...)
+}
@@ -0,0 +1,91 @@
+package tagset
+
+import (
+	"testing"
+
+	"github.com/djmitche/tagset/ident"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryBuiltins(t *testing.T) {
+	names := Registered()
+	require.Contains(t, names, "null")
+	require.Contains(t, names, "intern")
+	require.Contains(t, names, "revolving")
+	require.Contains(t, names, "threadsafe")
+	require.Contains(t, names, "sharded")
+}
+
+func TestNewFromSpec(t *testing.T) {
+	f, err := NewFromSpec("intern{capacity:1000}")
+	require.NoError(t, err)
+	require.IsType(t, &InternFoundry{}, f)
+	require.Equal(t, 2048, len(f.(*InternFoundry).byParseHash.slots))
+
+	f, err = NewFromSpec("null")
+	require.NoError(t, err)
+	require.IsType(t, &NullFoundry{}, f)
+
+	f, err = NewFromSpec("null{serialization:eager}")
+	require.NoError(t, err)
+	require.True(t, f.(*NullFoundry).eager)
+
+	f, err = NewFromSpec("intern{serialization:lazy}")
+	require.NoError(t, err)
+	require.False(t, f.(*InternFoundry).eager)
+
+	f, err = NewFromSpec("intern{keyDedup:last}")
+	require.NoError(t, err)
+	require.Equal(t, LastKeyWins, f.(*InternFoundry).keyDedup)
+
+	f, err = NewFromSpec("intern{unionCacheSize:100}")
+	require.NoError(t, err)
+	require.NotNil(t, f.(*InternFoundry).unions)
+
+	f, err = NewFromSpec("intern{contentInterning:true}")
+	require.NoError(t, err)
+	require.NotNil(t, f.(*InternFoundry).byHash)
+
+	_, err = NewFromSpec("intern{contentInterning:maybe}")
+	require.Error(t, err)
+
+	_, err = NewFromSpec("null{keyDedup:middle}")
+	require.Error(t, err)
+
+	_, err = NewFromSpec("null{serialization:sometimes}")
+	require.Error(t, err)
+
+	_, err = NewFromSpec("nosuch")
+	require.Error(t, err)
+
+	_, err = NewFromSpec("intern{bogus:1}")
+	require.Error(t, err)
+
+	_, err = New("intern", ident.Params{"capacity": -1})
+	require.Error(t, err)
+
+	// with several bad parameters, the first in order is reported
+	for i := 0; i < 10; i++ {
+		_, err = New("revolving", ident.Params{"size": "x", "rotateAfter": "y", "maxEntries": "z"})
+		require.EqualError(t, err,
+			`creating tagset foundry "revolving": parameter size must be an integer, got "x"`)
+		_, err = New("null", ident.Params{"trimSpace": 1, "truncate": 2, "maxTags": "x"})
+		require.EqualError(t, err,
+			`creating tagset foundry "null": parameter trimSpace must be a boolean, got int`)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("test-custom", func(params ident.Params) (Foundry, error) {
+		return NewNullFoundry()
+	})
+	require.Contains(t, Registered(), "test-custom")
+
+	f, err := NewFromSpec("test-custom")
+	require.NoError(t, err)
+	require.IsType(t, &NullFoundry{}, f)
+
+	require.Panics(t, func() {
+		Register("test-custom", func(params ident.Params) (Foundry, error) { return nil, nil })
+	})
+}
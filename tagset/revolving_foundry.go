@@ -0,0 +1,174 @@
+package tagset
+
+import (
+	"time"
+
+	"github.com/djmitche/tagset/ident"
+	"github.com/twmb/murmur3"
+)
+
+/* IMPLEMENTATION NOTES
+ *
+ * Each generation is a tagsetTable keyed by the hash of the parse input.
+ * Parses search the generations from newest to oldest, and a hit in an older
+ * generation is copied to the newest, so TagSets in active use survive
+ * rotation.  A rotation adds a new, empty generation and drops the oldest.
+ * Rotation occurs after a number of parses, after an interval of time, or when
+ * the total number of entries reaches a limit, whichever comes first.
+ *
+ * Evictions are counted without walking the dropped generation.  An entry in
+ * a generation can only appear in a newer generation by being promoted from
+ * it (or from a generation promoted from it), and once promoted it remains in
+ * a newer generation until this one is dropped.  So each generation counts
+ * its promotions, and the evictions on dropping it are its length less that
+ * count.
+ */
+
+// A RevolvingFoundry interns parsed TagSets, like InternFoundry, but bounds
+// the memory used by its parse cache by dividing it into generations and
+// periodically dropping the oldest, in the same fashion as
+// ident.RevolvingFoundry.  The effect is similar to a batched
+// least-recently-used cache.  A RevolvingFoundry is not threadsafe and must
+// not be accessed concurrently.
+type RevolvingFoundry struct {
+	// Fallback for operations other than Parse
+	NullFoundry
+
+	capacity    int
+	rotateAfter int
+	rotateEvery time.Duration
+	maxEntries  int
+	clock       func() time.Time
+
+	// parses since the last rotation, and the time of the last rotation
+	count      int
+	lastRotate time.Time
+
+	// parse caches, newest first
+	generations []*parseGeneration
+
+	// Count of parses, and misses in the parse cache
+	Parses, ParseMisses uint64
+
+	// Count of rotations, and of TagSets evicted from the parse cache by
+	// rotations (those not also present in a newer generation)
+	Rotations, Evictions uint64
+}
+
+// A parseGeneration is one generation of a RevolvingFoundry's parse cache.
+type parseGeneration struct {
+	*tagsetTable
+
+	// number of entries promoted from this generation to the newest
+	promoted int
+}
+
+// Create a RevolvingFoundry with the number of generations given by
+// WithGenerations (default 3), rotating after the number of parses given by
+// WithRotateAfter (default 5000), after the interval given by WithRotateEvery
+// (default never), or when the number of entries given by WithMaxEntries
+// (default 1,000,000) is reached.  WithCapacity pre-sizes each generation, and
+// WithClock sets the clock used for time-based rotation.  Other options are as
+// for NewNullFoundry.
+func NewRevolvingFoundry(opts ...Option) (*RevolvingFoundry, error) {
+	o, err := applyOptions(opts, optCapacity, optGenerations, optRotateAfter,
+		optRotateEvery, optMaxEntries, optClock, optSerialization, optKeyDedup,
+		optParseOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	generations := make([]*parseGeneration, o.generations)
+	for i := range generations {
+		generations[i] = &parseGeneration{tagsetTable: newTagsetTable(o.capacity)}
+	}
+
+	return &RevolvingFoundry{
+		NullFoundry: newNullFoundry(o),
+		capacity:    o.capacity,
+		rotateAfter: o.rotateAfter,
+		rotateEvery: o.rotateEvery,
+		maxEntries:  o.maxEntries,
+		clock:       o.clock,
+		lastRotate:  o.clock(),
+		generations: generations,
+	}, nil
+}
+
+func (f *RevolvingFoundry) Parse(foundry ident.Foundry, rawTags []byte) *TagSet {
+	return f.cachedParse(rawTags, func() *TagSet {
+		return f.NullFoundry.Parse(foundry, rawTags)
+	})
+}
+
+func (f *RevolvingFoundry) cachedParse(rawTags []byte, miss func() *TagSet) *TagSet {
+	f.Parses++
+	f.count++
+	if f.rotateAfter > 0 && f.count > f.rotateAfter {
+		f.rotate()
+	} else if f.rotateEvery > 0 && f.clock().Sub(f.lastRotate) >= f.rotateEvery {
+		f.rotate()
+	}
+
+	rawHashH, rawHashL := murmur3.Sum128(rawTags)
+	for i, gen := range f.generations {
+		existing := gen.get(rawHashH, rawHashL)
+		if existing != nil {
+			// if this hit was not in the newest generation, add it there,
+			// counting the promotion first in case insert drops gen
+			if i > 0 {
+				gen.promoted++
+				f.insert(rawHashH, rawHashL, existing)
+			}
+			return existing
+		}
+	}
+
+	f.ParseMisses++
+	fresh := miss()
+	f.insert(rawHashH, rawHashL, fresh)
+	return fresh
+}
+
+func (f *RevolvingFoundry) ParseChecked(foundry ident.Foundry, rawTags []byte) (*TagSet, error) {
+	return f.parseChecked(f, foundry, rawTags)
+}
+
+// Len returns the number of entries in the parse cache, across all
+// generations.  A TagSet present in several generations is counted in each.
+func (f *RevolvingFoundry) Len() int {
+	n := 0
+	for _, gen := range f.generations {
+		n += gen.len()
+	}
+	return n
+}
+
+// insert adds an entry to the newest generation, first rotating as necessary
+// to stay within maxEntries
+func (f *RevolvingFoundry) insert(hashH, hashL uint64, ts *TagSet) {
+	if f.maxEntries > 0 {
+		for f.Len() >= f.maxEntries {
+			f.rotate()
+		}
+	}
+	f.generations[0].insert(hashH, hashL, ts)
+}
+
+// Insert a new generation at the beginning of the rotation, dropping the
+// oldest.
+func (f *RevolvingFoundry) rotate() {
+	dropped := f.generations[len(f.generations)-1]
+
+	newGenerations := make([]*parseGeneration, len(f.generations))
+	newGenerations[0] = &parseGeneration{tagsetTable: newTagsetTable(f.capacity)}
+	copy(newGenerations[1:], f.generations[:len(f.generations)-1])
+	f.generations = newGenerations
+
+	// entries promoted to a newer generation were not evicted
+	f.Evictions += uint64(dropped.len() - dropped.promoted)
+
+	f.Rotations++
+	f.count = 0
+	f.lastRotate = f.clock()
+}
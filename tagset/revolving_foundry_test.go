@@ -0,0 +1,157 @@
+package tagset_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/djmitche/tagset/ident"
+	"github.com/djmitche/tagset/tagset"
+	"github.com/djmitche/tagset/tagset/tagsettest"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type RevolvingFoundrySuite struct {
+	tagsettest.FoundrySuite
+}
+
+func TestRevolvingFoundry(t *testing.T) {
+	suite.Run(t, &RevolvingFoundrySuite{
+		FoundrySuite: tagsettest.FoundrySuite{
+			NewFoundry: func() tagset.Foundry { return tagset.Must(tagset.NewRevolvingFoundry()) },
+			Interns:    true,
+		},
+	})
+}
+
+func newRevolvingFoundry(t *testing.T, opts ...tagset.Option) *tagset.RevolvingFoundry {
+	f, err := tagset.NewRevolvingFoundry(opts...)
+	require.NoError(t, err)
+	return f
+}
+
+func TestRevolvingFoundryRotateAfter(t *testing.T) {
+	idFoundry := ident.Must(ident.NewInternFoundry())
+	f := newRevolvingFoundry(t, tagset.WithGenerations(2), tagset.WithRotateAfter(10))
+
+	a := f.Parse(idFoundry, []byte("a"))
+	for i := 0; i < 9; i++ {
+		f.Parse(idFoundry, []byte(fmt.Sprintf("x:%d", i)))
+	}
+	require.Equal(t, uint64(0), f.Rotations)
+
+	// this parse rotates, but `a` is still in the older generation, and is
+	// promoted to the newer generation
+	require.True(t, a == f.Parse(idFoundry, []byte("a")))
+	require.Equal(t, uint64(1), f.Rotations)
+	require.Equal(t, uint64(0), f.Evictions)
+
+	// after another rotation, the x:%d are evicted but `a` is not
+	for i := 0; i < 11; i++ {
+		f.Parse(idFoundry, []byte(fmt.Sprintf("y:%d", i)))
+	}
+	require.Equal(t, uint64(2), f.Rotations)
+	require.Equal(t, uint64(9), f.Evictions)
+	require.True(t, a == f.Parse(idFoundry, []byte("a")))
+
+	// after two more rotations without accessing `a`, it is evicted
+	for i := 0; i < 25; i++ {
+		f.Parse(idFoundry, []byte(fmt.Sprintf("z:%d", i)))
+	}
+	require.False(t, a == f.Parse(idFoundry, []byte("a")))
+	require.Equal(t, f.Parses-f.ParseMisses, uint64(2))
+}
+
+func TestRevolvingFoundryRotateEvery(t *testing.T) {
+	idFoundry := ident.Must(ident.NewInternFoundry())
+	now := time.Unix(1000, 0)
+	clock := func() time.Time { return now }
+	f := newRevolvingFoundry(t,
+		tagset.WithGenerations(2),
+		tagset.WithRotateAfter(0),
+		tagset.WithRotateEvery(time.Minute),
+		tagset.WithClock(clock))
+
+	a := f.Parse(idFoundry, []byte("a"))
+	for i := 0; i < 100; i++ {
+		f.Parse(idFoundry, []byte(fmt.Sprintf("x:%d", i)))
+	}
+	require.Equal(t, uint64(0), f.Rotations)
+
+	now = now.Add(59 * time.Second)
+	f.Parse(idFoundry, []byte("b"))
+	require.Equal(t, uint64(0), f.Rotations)
+
+	now = now.Add(time.Second)
+	f.Parse(idFoundry, []byte("b"))
+	require.Equal(t, uint64(1), f.Rotations)
+
+	now = now.Add(time.Minute)
+	f.Parse(idFoundry, []byte("b"))
+	require.Equal(t, uint64(2), f.Rotations)
+	require.False(t, a == f.Parse(idFoundry, []byte("a")))
+}
+
+func TestRevolvingFoundryMaxEntries(t *testing.T) {
+	idFoundry := ident.Must(ident.NewInternFoundry())
+	f := newRevolvingFoundry(t,
+		tagset.WithRotateAfter(0),
+		tagset.WithMaxEntries(100))
+
+	for i := 0; i < 10000; i++ {
+		f.Parse(idFoundry, []byte(fmt.Sprintf("x:%d", i)))
+		require.LessOrEqual(t, f.Len(), 100)
+	}
+	require.Greater(t, f.Rotations, uint64(0))
+	require.Greater(t, f.Evictions, uint64(9000))
+}
+
+func TestRevolvingFoundryPromoteAtMaxEntries(t *testing.T) {
+	idFoundry := ident.Must(ident.NewInternFoundry())
+	f := newRevolvingFoundry(t,
+		tagset.WithGenerations(2),
+		tagset.WithRotateAfter(2),
+		tagset.WithMaxEntries(3))
+
+	a := f.Parse(idFoundry, []byte("a"))
+	f.Parse(idFoundry, []byte("b"))
+	f.Parse(idFoundry, []byte("c"))
+	require.Equal(t, uint64(1), f.Rotations)
+	require.Equal(t, 3, f.Len())
+
+	// promoting `a` rotates to stay within maxEntries, dropping the
+	// generation it came from; `a` is not counted as evicted, but `b` is
+	require.True(t, a == f.Parse(idFoundry, []byte("a")))
+	require.Equal(t, uint64(2), f.Rotations)
+	require.Equal(t, uint64(1), f.Evictions)
+	require.Equal(t, 2, f.Len())
+
+	require.True(t, a == f.Parse(idFoundry, []byte("a")))
+	f.Parse(idFoundry, []byte("b"))
+	require.Equal(t, uint64(4), f.ParseMisses)
+}
+
+func TestRevolvingFoundryOptions(t *testing.T) {
+	_, err := tagset.NewRevolvingFoundry(tagset.WithGenerations(1))
+	require.Error(t, err)
+	_, err = tagset.NewRevolvingFoundry(tagset.WithRotateAfter(-1))
+	require.Error(t, err)
+	_, err = tagset.NewRevolvingFoundry(tagset.WithRotateEvery(-time.Second))
+	require.Error(t, err)
+	_, err = tagset.NewRevolvingFoundry(tagset.WithMaxEntries(-1))
+	require.Error(t, err)
+	_, err = tagset.NewRevolvingFoundry(tagset.WithClock(nil))
+	require.Error(t, err)
+}
+
+func TestRevolvingFoundryFromSpec(t *testing.T) {
+	f, err := tagset.NewFromSpec("revolving{size:4,rotateAfter:100,rotateEvery:5m,maxEntries:1000}")
+	require.NoError(t, err)
+	require.IsType(t, &tagset.RevolvingFoundry{}, f)
+
+	_, err = tagset.NewFromSpec("revolving{rotateEvery:later}")
+	require.Error(t, err)
+	_, err = tagset.NewFromSpec("revolving{size:1}")
+	require.Error(t, err)
+}
@@ -0,0 +1,232 @@
+package tagset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"sync"
+	"unicode/utf8"
+)
+
+// A Serializer produces a serialized form of a TagSet, for a particular
+// destination.  Comparable Serializers are used as keys to cache
+// serializations on each TagSet; see TagSet.SerializeAs.
+type Serializer interface {
+	// Append appends the serialization of the given TagSet to dst, and
+	// returns the extended slice.  Tags are serialized in hash order.
+	Append(dst []byte, ts *TagSet) []byte
+}
+
+var (
+	// CommaSerializer produces comma-joined tags, such as `a:1,b:2`.  This is
+	// the same as TagSet.Serialization.
+	CommaSerializer Serializer = commaSerializer{}
+
+	// DogStatsDSerializer produces a DogStatsD tag suffix, such as
+	// `|#a:1,b:2`, or nothing for an empty TagSet.
+	DogStatsDSerializer Serializer = dogStatsDSerializer{}
+
+	// JSONSerializer produces a JSON array of strings, such as
+	// `["a:1","b:2"]`.  Invalid UTF-8 is replaced with U+FFFD, as done by
+	// encoding/json.
+	JSONSerializer Serializer = jsonSerializer{}
+
+	// PrometheusSerializer produces a Prometheus label set, such as
+	// `{a="1",b="2"}`, or nothing for an empty TagSet.  Each tag is split at
+	// its first colon into a label name and value; a tag without a colon has
+	// an empty value.  Characters not permitted in label names are replaced
+	// with `_`.  Tags with the same name produce repeated labels, which
+	// Prometheus will reject, so callers should ensure names are unique.
+	PrometheusSerializer Serializer = prometheusSerializer{}
+)
+
+type commaSerializer struct{}
+
+func (commaSerializer) Append(dst []byte, ts *TagSet) []byte {
+	return ts.AppendSerialization(dst)
+}
+
+type dogStatsDSerializer struct{}
+
+var dogStatsDOptions = SerializationOptions{Prefix: []byte("|#")}
+
+func (dogStatsDSerializer) Append(dst []byte, ts *TagSet) []byte {
+	return ts.AppendSerializationWith(dst, dogStatsDOptions)
+}
+
+type jsonSerializer struct{}
+
+var jsonOptions = SerializationOptions{Escape: AppendJSONString}
+
+func (jsonSerializer) Append(dst []byte, ts *TagSet) []byte {
+	dst = append(dst, '[')
+	dst = ts.AppendSerializationWith(dst, jsonOptions)
+	return append(dst, ']')
+}
+
+// AppendJSONString appends the given bytes as a quoted JSON string, and
+// returns the extended slice.  Invalid UTF-8 is replaced with U+FFFD.
+func AppendJSONString(dst []byte, s []byte) []byte {
+	const hex = "0123456789abcdef"
+
+	dst = append(dst, '"')
+	for len(s) > 0 {
+		c := s[0]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				dst = append(dst, '\\', c)
+			case c == '\n':
+				dst = append(dst, '\\', 'n')
+			case c == '\r':
+				dst = append(dst, '\\', 'r')
+			case c == '\t':
+				dst = append(dst, '\\', 't')
+			case c < 0x20:
+				dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			default:
+				dst = append(dst, c)
+			}
+			s = s[1:]
+			continue
+		}
+
+		r, size := utf8.DecodeRune(s)
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, "\ufffd"...)
+		} else {
+			dst = append(dst, s[:size]...)
+		}
+		s = s[size:]
+	}
+	return append(dst, '"')
+}
+
+type protobufSerializer struct {
+	// key is the field number and wire type, as encoded before each tag
+	key uint64
+}
+
+// maxProtobufField is the largest field number permitted by protobuf
+const maxProtobufField = 1<<29 - 1
+
+// NewProtobufSerializer creates a Serializer producing the protobuf wire
+// encoding of a `repeated string` field with the given field number,
+// containing the tags, suitable for inclusion in a larger message.
+func NewProtobufSerializer(field int) (Serializer, error) {
+	if field < 1 || field > maxProtobufField {
+		return nil, fmt.Errorf("invalid protobuf field number %d", field)
+	}
+	// wire type 2 is length-delimited
+	return protobufSerializer{key: uint64(field)<<3 | 2}, nil
+}
+
+func (s protobufSerializer) Append(dst []byte, ts *TagSet) []byte {
+	var varint [binary.MaxVarintLen64]byte
+	for _, t := range ts.tags {
+		tag := t.Bytes()
+		dst = append(dst, varint[:binary.PutUvarint(varint[:], s.key)]...)
+		dst = append(dst, varint[:binary.PutUvarint(varint[:], uint64(len(tag)))]...)
+		dst = append(dst, tag...)
+	}
+	return dst
+}
+
+type prometheusSerializer struct{}
+
+func (prometheusSerializer) Append(dst []byte, ts *TagSet) []byte {
+	if ts.size == 0 {
+		return dst
+	}
+	dst = append(dst, '{')
+	for i, t := range ts.tags {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		name, value := t.Bytes(), []byte{}
+		if colon := bytes.IndexByte(name, ':'); colon >= 0 {
+			name, value = name[:colon], name[colon+1:]
+		}
+		dst = appendPrometheusName(dst, name)
+		dst = append(dst, '=', '"')
+		for _, c := range value {
+			switch c {
+			case '\\', '"':
+				dst = append(dst, '\\', c)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			default:
+				dst = append(dst, c)
+			}
+		}
+		dst = append(dst, '"')
+	}
+	return append(dst, '}')
+}
+
+// appendPrometheusName appends a valid Prometheus label name, matching
+// `[a-zA-Z_][a-zA-Z0-9_]*`, replacing invalid characters with `_`.
+func appendPrometheusName(dst []byte, name []byte) []byte {
+	if len(name) == 0 || (name[0] >= '0' && name[0] <= '9') {
+		dst = append(dst, '_')
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_':
+			dst = append(dst, c)
+		default:
+			dst = append(dst, '_')
+		}
+	}
+	return dst
+}
+
+// A formatCache holds the serializations of a TagSet in formats other than
+// the default.  Few formats are expected per TagSet, so this is a short
+// slice rather than a map.
+type formatCache struct {
+	sync.RWMutex
+	entries []formatEntry
+}
+
+type formatEntry struct {
+	serializer    Serializer
+	serialization []byte
+}
+
+// SerializeAs returns the serialization of this tagset produced by the given
+// Serializer.  For comparable Serializers, each serialization is computed at
+// most once and cached, so the returned value MUST not be modified.  Others
+// are computed on each call.  This is threadsafe.
+func (ts *TagSet) SerializeAs(s Serializer) []byte {
+	if _, ok := s.(commaSerializer); ok {
+		return ts.Serialization()
+	}
+	if !reflect.TypeOf(s).Comparable() {
+		return s.Append(make([]byte, 0, len(ts.tags)*avgTagSize), ts)
+	}
+
+	cache := &ts.extra().formats
+
+	cache.RLock()
+	for _, e := range cache.entries {
+		if e.serializer == s {
+			cache.RUnlock()
+			return e.serialization
+		}
+	}
+	cache.RUnlock()
+
+	cache.Lock()
+	defer cache.Unlock()
+	// check again, in case another goroutine got here first
+	for _, e := range cache.entries {
+		if e.serializer == s {
+			return e.serialization
+		}
+	}
+	serialization := s.Append(make([]byte, 0, len(ts.tags)*avgTagSize), ts)
+	cache.entries = append(cache.entries, formatEntry{s, serialization})
+	return serialization
+}
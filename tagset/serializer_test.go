@@ -0,0 +1,153 @@
+package tagset
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommaSerializer(t *testing.T) {
+	ts := tagsetOf("a:1", "b:2")
+	require.Equal(t, ts.Serialization(), ts.SerializeAs(CommaSerializer))
+	require.Equal(t, []byte("x"), CommaSerializer.Append([]byte("x"), emptyTagSet))
+}
+
+func TestDogStatsDSerializer(t *testing.T) {
+	ts := tagsetOf("a:1", "b:2")
+	require.Equal(t, append([]byte("|#"), ts.Serialization()...), ts.SerializeAs(DogStatsDSerializer))
+	require.Equal(t, []byte("metric:1|c"), DogStatsDSerializer.Append([]byte("metric:1|c"), emptyTagSet))
+}
+
+func TestJSONSerializer(t *testing.T) {
+	ts := tagsetOf("a:1", `quote:"`, "back\\slash", "ctl:\x01\n", "utf8:é", "bad:\xff")
+
+	// the result should match encoding/json, in the order of the tags
+	exp, err := json.Marshal(ts.Strings())
+	require.NoError(t, err)
+	require.Equal(t, string(exp), string(ts.SerializeAs(JSONSerializer)))
+
+	var decoded []string
+	require.NoError(t, json.Unmarshal(ts.SerializeAs(JSONSerializer), &decoded))
+	require.Len(t, decoded, 6)
+
+	require.Equal(t, []byte("[]"), emptyTagSet.SerializeAs(JSONSerializer))
+}
+
+func TestProtobufSerializer(t *testing.T) {
+	_, err := NewProtobufSerializer(0)
+	require.Error(t, err)
+	_, err = NewProtobufSerializer(maxProtobufField + 1)
+	require.Error(t, err)
+
+	s, err := NewProtobufSerializer(20)
+	require.NoError(t, err)
+
+	ts := tagsetOf("a:1", "bb:22")
+	buf := ts.SerializeAs(s)
+
+	// decode the wire format
+	var tags []string
+	for len(buf) > 0 {
+		key, n := binary.Uvarint(buf)
+		require.Greater(t, n, 0)
+		require.Equal(t, uint64(20<<3|2), key)
+		buf = buf[n:]
+		length, n := binary.Uvarint(buf)
+		require.Greater(t, n, 0)
+		buf = buf[n:]
+		tags = append(tags, string(buf[:length]))
+		buf = buf[length:]
+	}
+	require.Equal(t, ts.Strings(), tags)
+
+	// different field numbers are cached separately
+	s2, err := NewProtobufSerializer(3)
+	require.NoError(t, err)
+	require.Equal(t, byte(3<<3|2), ts.SerializeAs(s2)[0])
+}
+
+func TestPrometheusSerializer(t *testing.T) {
+	ts := tagsetOf("env:prod")
+	require.Equal(t, `{env="prod"}`, string(ts.SerializeAs(PrometheusSerializer)))
+
+	ts = tagsetOf(`path:/a"b\c`)
+	require.Equal(t, `{path="/a\"b\\c"}`, string(ts.SerializeAs(PrometheusSerializer)))
+
+	ts = tagsetOf("novalue")
+	require.Equal(t, `{novalue=""}`, string(ts.SerializeAs(PrometheusSerializer)))
+
+	ts = tagsetOf("1st.key-x:v:w")
+	require.Equal(t, `{_1st_key_x="v:w"}`, string(ts.SerializeAs(PrometheusSerializer)))
+
+	ts = tagsetOf(":v")
+	require.Equal(t, `{_="v"}`, string(ts.SerializeAs(PrometheusSerializer)))
+
+	require.Equal(t, []byte{}, emptyTagSet.SerializeAs(PrometheusSerializer))
+}
+
+// countingSerializer counts calls to Append
+type countingSerializer struct {
+	count *int
+}
+
+func (s countingSerializer) Append(dst []byte, ts *TagSet) []byte {
+	*s.count++
+	return append(dst, "counted"...)
+}
+
+func TestSerializeAsCaches(t *testing.T) {
+	ts := tagsetOf("a", "b")
+	count := 0
+	s := countingSerializer{&count}
+
+	first := ts.SerializeAs(s)
+	second := ts.SerializeAs(s)
+	require.Equal(t, []byte("counted"), first)
+	require.Equal(t, 1, count)
+	require.True(t, &first[0] == &second[0])
+
+	// a distinct serializer value is cached separately
+	other := 0
+	ts.SerializeAs(countingSerializer{&other})
+	require.Equal(t, 1, count)
+	require.Equal(t, 1, other)
+}
+
+// sliceSerializer is not comparable, so its serializations are not cached
+type sliceSerializer struct {
+	prefix []byte
+}
+
+func (s sliceSerializer) Append(dst []byte, ts *TagSet) []byte {
+	return ts.AppendSerializationWith(dst, SerializationOptions{Prefix: s.prefix})
+}
+
+func TestSerializeAsNotComparable(t *testing.T) {
+	ts := tagsetOf("a")
+	require.Equal(t, []byte("x=a"), ts.SerializeAs(sliceSerializer{[]byte("x=")}))
+	require.Equal(t, []byte("y=a"), ts.SerializeAs(sliceSerializer{[]byte("y=")}))
+	require.Nil(t, ts.extra().formats.entries)
+}
+
+func TestSerializeAsConcurrent(t *testing.T) {
+	ts := tagsetOf("a:1", "b:2", "c:3")
+	serializers := []Serializer{CommaSerializer, DogStatsDSerializer, JSONSerializer, PrometheusSerializer}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := range serializers {
+				s := serializers[(i+w)%len(serializers)]
+				if len(ts.SerializeAs(s)) == 0 {
+					t.Errorf("empty serialization")
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,118 @@
+package tagset
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/djmitche/tagset/ident"
+	"github.com/twmb/murmur3"
+)
+
+/* IMPLEMENTATION NOTES
+ *
+ * The parse cache is divided into shards, each a tagsetTable protected by its
+ * own mutex, selected by the raw hash of the parse input.  Concurrent parses
+ * of different inputs thus rarely contend for the same lock.  The lock is not
+ * held while parsing on a miss, so two goroutines may parse the same input
+ * concurrently; the first to insert its result wins, and the other's result
+ * is discarded so that all callers see the same TagSet.
+ *
+ * Operations other than Parse are those of NullFoundry, which has no mutable
+ * state and is safe for concurrent use.
+ */
+
+// A ShardedFoundry interns parsed TagSets, like InternFoundry, but is
+// threadsafe, using a sharded parse cache to reduce lock contention.  The
+// ident.Foundry passed to Parse must also be threadsafe.
+type ShardedFoundry struct {
+	// Count of parses, and misses in the parse cache.  These are updated
+	// atomically, and must be read with atomic.LoadUint64.  They are first in
+	// the struct to ensure 64-bit alignment.
+	Parses, ParseMisses uint64
+
+	// Fallback for operations other than Parse
+	NullFoundry
+
+	shards []parseShard
+	mask   uint64
+}
+
+type parseShard struct {
+	sync.Mutex
+	byParseHash *tagsetTable
+}
+
+// Create a ShardedFoundry.  The WithShards option sets the number of shards
+// (default 16), and the WithCapacity option pre-sizes the parse cache, divided
+// evenly between the shards.  Other options are as for NewNullFoundry.
+func NewShardedFoundry(opts ...Option) (*ShardedFoundry, error) {
+	o, err := applyOptions(opts, optShards, optCapacity, optSerialization,
+		optKeyDedup, optParseOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// round the number of shards up to a power of two
+	n := 1
+	for n < o.shards {
+		n *= 2
+	}
+
+	shards := make([]parseShard, n)
+	for i := range shards {
+		shards[i].byParseHash = newTagsetTable((o.capacity + n - 1) / n)
+	}
+
+	return &ShardedFoundry{
+		NullFoundry: newNullFoundry(o),
+		shards:      shards,
+		mask:        uint64(n - 1),
+	}, nil
+}
+
+func (f *ShardedFoundry) Parse(foundry ident.Foundry, rawTags []byte) *TagSet {
+	return f.cachedParse(rawTags, func() *TagSet {
+		return f.NullFoundry.Parse(foundry, rawTags)
+	})
+}
+
+func (f *ShardedFoundry) cachedParse(rawTags []byte, miss func() *TagSet) *TagSet {
+	atomic.AddUint64(&f.Parses, 1)
+	rawHashH, rawHashL := murmur3.Sum128(rawTags)
+	// the table uses the low bits of hashL, so select the shard with hashH
+	shard := &f.shards[rawHashH&f.mask]
+
+	shard.Lock()
+	existing := shard.byParseHash.get(rawHashH, rawHashL)
+	shard.Unlock()
+	if existing != nil {
+		return existing
+	}
+
+	atomic.AddUint64(&f.ParseMisses, 1)
+	fresh := miss()
+
+	shard.Lock()
+	defer shard.Unlock()
+	if existing := shard.byParseHash.get(rawHashH, rawHashL); existing != nil {
+		return existing
+	}
+	shard.byParseHash.insert(rawHashH, rawHashL, fresh)
+	return fresh
+}
+
+func (f *ShardedFoundry) ParseChecked(foundry ident.Foundry, rawTags []byte) (*TagSet, error) {
+	return f.parseChecked(f, foundry, rawTags)
+}
+
+// Len returns the number of entries in the parse cache
+func (f *ShardedFoundry) Len() int {
+	n := 0
+	for i := range f.shards {
+		shard := &f.shards[i]
+		shard.Lock()
+		n += shard.byParseHash.len()
+		shard.Unlock()
+	}
+	return n
+}
@@ -0,0 +1,92 @@
+package tagset_test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/djmitche/tagset/ident"
+	"github.com/djmitche/tagset/tagset"
+	"github.com/djmitche/tagset/tagset/tagsettest"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type ShardedFoundrySuite struct {
+	tagsettest.FoundrySuite
+}
+
+func TestShardedFoundry(t *testing.T) {
+	suite.Run(t, &ShardedFoundrySuite{
+		FoundrySuite: tagsettest.FoundrySuite{
+			NewFoundry: func() tagset.Foundry { return tagset.Must(tagset.NewShardedFoundry()) },
+			Interns:    true,
+			Threadsafe: true,
+		},
+	})
+}
+
+func TestShardedFoundryOneShard(t *testing.T) {
+	suite.Run(t, &ShardedFoundrySuite{
+		FoundrySuite: tagsettest.FoundrySuite{
+			NewFoundry: func() tagset.Foundry { return tagset.Must(tagset.NewShardedFoundry(tagset.WithShards(1))) },
+			Interns:    true,
+			Threadsafe: true,
+		},
+	})
+}
+
+func TestShardedFoundryOptions(t *testing.T) {
+	_, err := tagset.NewShardedFoundry(tagset.WithShards(0))
+	require.Error(t, err)
+
+	f, err := tagset.NewFromSpec("sharded{shards:5,capacity:1000}")
+	require.NoError(t, err)
+	require.IsType(t, &tagset.ShardedFoundry{}, f)
+
+	f, err = tagset.NewFromSpec("threadsafe{inner:revolving{size:2}}")
+	require.NoError(t, err)
+	require.IsType(t, &tagset.ThreadsafeFoundry{}, f)
+
+	_, err = tagset.NewFromSpec("threadsafe{inner:nosuch}")
+	require.Error(t, err)
+}
+
+func TestShardedFoundryConcurrent(t *testing.T) {
+	idFoundry := ident.Must(ident.NewThreadsafeFoundry(ident.Must(ident.NewInternFoundry())))
+	f, err := tagset.NewShardedFoundry(tagset.WithShards(4))
+	require.NoError(t, err)
+
+	const workers = 8
+	const count = 1000
+	const distinct = 100
+	results := make([][]*tagset.TagSet, workers)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func(w int) {
+			defer wg.Done()
+			results[w] = make([]*tagset.TagSet, distinct)
+			for i := 0; i < count; i++ {
+				ts := f.Parse(idFoundry, []byte(fmt.Sprintf("x:%d,y", i%distinct)))
+				results[w][i%distinct] = ts
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// every goroutine saw the same TagSet for each input
+	for w := 1; w < workers; w++ {
+		for i := 0; i < distinct; i++ {
+			require.True(t, results[0][i] == results[w][i])
+		}
+	}
+
+	require.Equal(t, uint64(workers*count), atomic.LoadUint64(&f.Parses))
+	misses := atomic.LoadUint64(&f.ParseMisses)
+	require.GreaterOrEqual(t, misses, uint64(distinct))
+	require.LessOrEqual(t, misses, uint64(distinct*workers))
+	require.Equal(t, distinct, f.Len())
+}
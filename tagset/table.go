@@ -0,0 +1,96 @@
+package tagset
+
+import "github.com/djmitche/tagset/internal/hashtable"
+
+// A tagsetTable implements a hash table indexed by a 128-bit value represented
+// as a high and low uint64.  It uses open addressing with linear probing in a
+// power-of-two sized slot array, storing both halves of the hash inline in
+// each slot so that probing does not need to dereference the TagSets.  The
+// table grows when it is more than 3/4 full, as for the hashtable package, and
+// only when a new key is added.  See `hashMask` for help testing collisions.
+type tagsetTable struct {
+	slots []tagsetSlot
+	mask  uint64
+	count int
+}
+
+type tagsetSlot struct {
+	hashH, hashL uint64
+	ts           *TagSet
+}
+
+// Create a new tagsetTable, sized to hold `capacity` elements without growing.
+func newTagsetTable(capacity int) *tagsetTable {
+	size := hashtable.SizeFor(capacity)
+	return &tagsetTable{
+		slots: make([]tagsetSlot, size),
+		mask:  uint64(size - 1),
+	}
+}
+
+// Get a TagSet, if it is present in the hash table.
+func (tbl *tagsetTable) get(hashH, hashL uint64) *TagSet {
+	hashH &= hashMask
+	hashL &= hashMask
+
+	for i := hashL & tbl.mask; ; i = (i + 1) & tbl.mask {
+		slot := &tbl.slots[i]
+		if slot.ts == nil {
+			return nil
+		}
+		if slot.hashL == hashL && slot.hashH == hashH {
+			return slot.ts
+		}
+	}
+}
+
+// Insert a TagSet, overwriting any element already present with the same hash.
+func (tbl *tagsetTable) insert(hashH, hashL uint64, newElt *TagSet) {
+	hashH &= hashMask
+	hashL &= hashMask
+
+	i := hashL & tbl.mask
+	for ; tbl.slots[i].ts != nil; i = (i + 1) & tbl.mask {
+		slot := &tbl.slots[i]
+		if slot.hashL == hashL && slot.hashH == hashH {
+			slot.ts = newElt
+			return
+		}
+	}
+
+	// this is a new key, so grow first if necessary
+	if hashtable.MustGrow(tbl.count, len(tbl.slots)) {
+		tbl.grow()
+		i = tbl.emptySlot(hashL)
+	}
+	tbl.slots[i] = tagsetSlot{hashH: hashH, hashL: hashL, ts: newElt}
+	tbl.count++
+}
+
+// len returns the number of elements in the table
+func (tbl *tagsetTable) len() int {
+	return tbl.count
+}
+
+// grow doubles the size of the table, re-inserting all existing elements
+func (tbl *tagsetTable) grow() {
+	old := tbl.slots
+	tbl.slots = make([]tagsetSlot, len(old)*2)
+	tbl.mask = uint64(len(tbl.slots) - 1)
+
+	for _, slot := range old {
+		if slot.ts != nil {
+			tbl.slots[tbl.emptySlot(slot.hashL)] = slot
+		}
+	}
+}
+
+// emptySlot returns the index of the first empty slot in the probe sequence
+// for the given hash
+func (tbl *tagsetTable) emptySlot(hashL uint64) uint64 {
+	i := hashL & tbl.mask
+	for tbl.slots[i].ts != nil {
+		i = (i + 1) & tbl.mask
+	}
+	return i
+}
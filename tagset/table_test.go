@@ -0,0 +1,221 @@
+package tagset
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func fakeTagSetWithHash(hashH, hashL uint64) *TagSet {
+	return &TagSet{
+		hashH: hashH,
+		hashL: hashL,
+	}
+}
+
+func tableTestCount() uint64 {
+	count := uint64(hashMask)
+	if count > 1000 {
+		// too big to fill, so just use "some"
+		count = 1000
+	} else {
+		// hold back a bit from the maximum capacity of the table
+		count = count - 10
+	}
+	return count
+}
+
+// Check that tagsetTable behaves like a regular old map
+func TestTableWellBehaved(t *testing.T) {
+	count := tableTestCount()
+
+	tbl := newTagsetTable(int(count))
+	regularMap := map[string]*TagSet{}
+
+	for i := uint64(0); i < count; i++ {
+		h := rand.Uint64() & hashMask
+		l := rand.Uint64() & hashMask
+		k := fmt.Sprintf("%016x.%016x", h, l)
+
+		if _, found := regularMap[k]; found {
+			elt := tbl.get(h, l)
+			require.NotNil(t, elt)
+			require.Equal(t, h, elt.hashH)
+			require.Equal(t, l, elt.hashL)
+		} else {
+			require.Nil(t, tbl.get(h, l))
+		}
+
+		ts := fakeTagSetWithHash(h, l)
+		regularMap[k] = ts
+		tbl.insert(h, l, ts)
+	}
+
+	// now try to find all of those elements again..
+	for k, ts := range regularMap {
+		h := ts.hashH
+		l := ts.hashL
+		got := tbl.get(h, l)
+		require.Equal(t, ts, got, k)
+	}
+}
+
+func TestTableReplaceDoesNotGrow(t *testing.T) {
+	tbl := newTagsetTable(0)
+
+	// fill the table to its maximum load factor
+	size := len(tbl.slots)
+	for i := uint64(0); i < uint64(size*3/4); i++ {
+		tbl.insert(i, i, fakeTagSetWithHash(i, i))
+	}
+	require.Equal(t, size, len(tbl.slots))
+
+	// replacing an existing key does not grow the table
+	tbl.insert(0, 0, fakeTagSetWithHash(0, 0))
+	require.Equal(t, size, len(tbl.slots))
+
+	// adding a new key does
+	tbl.insert(100, 100, fakeTagSetWithHash(100, 100))
+	require.Equal(t, size*2, len(tbl.slots))
+	require.Equal(t, size*3/4+1, tbl.count)
+}
+
+func TestTableCollisions(t *testing.T) {
+	count := tableTestCount()
+	tbl := newTagsetTable(int(count))
+
+	base := uint64(0x123456789)
+
+	// insert (checking for nil), using hashes that are guaranteed
+	// to generate lots of collisions
+	for i := uint64(0); i < count; i++ {
+		hashH := (base + i/2) & hashMask
+		hashL := (base + i) & hashMask
+		ts := fakeTagSetWithHash(hashH, hashL)
+
+		require.Nil(t, tbl.get(hashH, hashL))
+		tbl.insert(hashH, hashL, ts)
+	}
+
+	// insert again (should overwrite)
+	for i := uint64(0); i < count; i++ {
+		hashH := (base + i/2) & hashMask
+		hashL := (base + i) & hashMask
+		elt := tbl.get(hashH, hashL)
+		require.Equal(t, hashH, elt.HashH())
+		require.Equal(t, hashL, elt.HashL())
+
+		ts := fakeTagSetWithHash(hashH, hashL)
+		tbl.insert(hashH, hashL, ts)
+	}
+
+	// get results
+	for i := uint64(0); i < count; i++ {
+		hashH := (base + i/2) & hashMask
+		hashL := (base + i) & hashMask
+
+		elt := tbl.get(hashH, hashL)
+		require.Equal(t, hashH, elt.HashH())
+		require.Equal(t, hashL, elt.HashL())
+	}
+}
+
+func BenchmarkTableInsert(b *testing.B) {
+	baseH := rand.Uint64()
+	baseL := rand.Uint64()
+	tbl := newTagsetTable(b.N)
+	n := uint64(b.N)
+
+	if n > hashMask {
+		b.Skipf("don't benchmark with small hashMask")
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	// we only store a single tagset, to avoid wasting time
+	// on this allocation
+	ts := fakeTagSetWithHash(baseH, baseL)
+
+	for i := uint64(0); i < n; i++ {
+		hashH := (baseH + i) & hashMask
+		hashL := (baseL + i) & hashMask
+		tbl.insert(hashH, hashL, ts)
+	}
+}
+
+// global place for benchmarks to write, to avoid optimization
+var benchTS *TagSet
+
+// tableBenchCount is the number of entries in the tables used for lookup
+// benchmarks
+const tableBenchCount = 100000
+
+// heapInUse returns the current heap allocation, after a GC
+func heapInUse() uint64 {
+	var ms runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&ms)
+	return ms.HeapAlloc
+}
+
+func BenchmarkTableGet(b *testing.B) {
+	hashes := make([][2]uint64, tableBenchCount)
+	for i := range hashes {
+		hashes[i] = [2]uint64{rand.Uint64() & hashMask, rand.Uint64() & hashMask}
+	}
+	ts := fakeTagSetWithHash(0, 0)
+
+	before := heapInUse()
+	tbl := newTagsetTable(0)
+	for _, h := range hashes {
+		tbl.insert(h[0], h[1], ts)
+	}
+	after := heapInUse()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		h := hashes[i%tableBenchCount]
+		benchTS = tbl.get(h[0], h[1])
+	}
+
+	b.ReportMetric(float64(after-before)/float64(tbl.len()), "B/entry")
+}
+
+// BenchmarkMapGet measures the equivalent lookups in a Go map, keyed on the
+// high hash, for comparison with BenchmarkTableGet.
+func BenchmarkMapGet(b *testing.B) {
+	type elt struct {
+		hashL uint64
+		ts    *TagSet
+	}
+	hashes := make([][2]uint64, tableBenchCount)
+	for i := range hashes {
+		hashes[i] = [2]uint64{rand.Uint64() & hashMask, rand.Uint64() & hashMask}
+	}
+	ts := fakeTagSetWithHash(0, 0)
+
+	before := heapInUse()
+	m := map[uint64]elt{}
+	for _, h := range hashes {
+		m[h[0]] = elt{h[1], ts}
+	}
+	after := heapInUse()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		h := hashes[i%tableBenchCount]
+		if e, found := m[h[0]]; found && e.hashL == h[1] {
+			benchTS = e.ts
+		}
+	}
+
+	b.ReportMetric(float64(after-before)/float64(len(m)), "B/entry")
+}
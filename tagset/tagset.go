@@ -1,7 +1,12 @@
 package tagset
 
 import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
 	"github.com/djmitche/tagset/ident"
+	"github.com/twmb/murmur3"
 )
 
 // A shared cache for the empty TagSet
@@ -18,16 +23,44 @@ type TagSet struct {
 	// size is the total number of tags in the tagset
 	size int
 
-	// tags contains a duplicate-free list of the tags in this set.  This
-	// is not necessarily sorted!
+	// tags contains a duplicate-free list of the tags in this set, sorted
+	// by hash as by ident.Sort.  The serialization is in the same order.
 	tags []ident.Ident
 
 	// hashH and hashL contain the hash of all tags in the set.  Hashes are
 	// computed from tag hashes in a way that is associative and commutative.
 	hashH, hashL uint64
 
-	// serialization of this tagset
+	// serialization of this tagset, computed on first use by Serialization
+	// unless the foundry computed it eagerly
+	serializeOnce sync.Once
 	serialization []byte
+
+	// serialized is set to 1, atomically, once serialization is available
+	// for use without serializeOnce
+	serialized uint32
+
+	// rarely used derived state, allocated on first use by extra
+	extraOnce sync.Once
+	lazyExtra *tagsetExtra
+}
+
+// A tagsetExtra holds the rarely used derived state of a TagSet, so that
+// TagSets which never use it, such as intermediate unions, carry only a
+// single pointer for it.
+type tagsetExtra struct {
+	// serializations in other formats, used by SerializeAs
+	formats formatCache
+
+	// index of tags by key, built on first use
+	keysOnce sync.Once
+	keys     *keyIndex
+}
+
+// extra returns the tagsetExtra for this tagset, allocating it if necessary
+func (ts *TagSet) extra() *tagsetExtra {
+	ts.extraOnce.Do(func() { ts.lazyExtra = &tagsetExtra{} })
+	return ts.lazyExtra
 }
 
 // Hash returns the 128-bit hash of this tagset, high word first
@@ -46,28 +79,66 @@ func (ts *TagSet) HashL() uint64 {
 }
 
 // Serialization returns the serialization of this tagset. The returned
-// value MUST not be modified.
+// value MUST not be modified.  Depending on the foundry, the serialization
+// may be computed on first use; this is threadsafe.
 func (ts *TagSet) Serialization() []byte {
+	ts.serializeOnce.Do(func() {
+		if ts.serialization == nil {
+			ts.serialization = ts.appendTags(make([]byte, 0, len(ts.tags)*avgTagSize))
+		}
+		atomic.StoreUint32(&ts.serialized, 1)
+	})
 	return ts.serialization
 }
 
-// has determines whether a tagset contains the given tag
-func (ts *TagSet) has(t ident.Ident) bool {
-	// TODO: this could be much, much faster!  Maybe build a set on
-	// each TagSet as required (with sync.Once)?
+// Len returns the number of tags in the tagset
+func (ts *TagSet) Len() int {
+	return ts.size
+}
 
-	for _, t2 := range ts.tags {
-		if t2.Equals(t) {
-			return true
+// Has determines whether a tagset contains the given tag
+func (ts *TagSet) Has(t ident.Ident) bool {
+	return ts.hasHash(t.HashH(), t.HashL())
+}
+
+// HasBytes determines whether a tagset contains a tag with the given bytes
+func (ts *TagSet) HasBytes(t []byte) bool {
+	return ts.hasHash(murmur3.Sum128(t))
+}
+
+// hasHash determines whether a tagset contains a tag with the given hash,
+// using a binary search of the sorted tags
+func (ts *TagSet) hasHash(hashH, hashL uint64) bool {
+	tags := ts.tags
+	i := sort.Search(len(tags), func(i int) bool {
+		th := tags[i].HashH()
+		return th > hashH || (th == hashH && tags[i].HashL() >= hashL)
+	})
+	return i < len(tags) && tags[i].HashH() == hashH && tags[i].HashL() == hashL
+}
+
+// ForEach calls the given function once for each tag in the TagSet, in
+// hash order, until it returns false.
+func (ts *TagSet) ForEach(f func(ident.Ident) bool) {
+	for _, t := range ts.tags {
+		if !f(t) {
+			return
 		}
 	}
+}
 
-	return false
+// AppendTags appends the tags in the TagSet to the given slice, in hash
+// order, and returns the extended slice.
+func (ts *TagSet) AppendTags(dst []ident.Ident) []ident.Ident {
+	return append(dst, ts.tags...)
 }
 
-// forEach calls the given function once for each tag in the TagSet
-func (ts *TagSet) forEach(f func(ident.Ident)) {
-	for _, t := range ts.tags {
-		f(t)
+// Strings returns the tags in the TagSet as strings, in hash order.
+// This allocates, and is intended for debugging and tests.
+func (ts *TagSet) Strings() []string {
+	rv := make([]string, len(ts.tags))
+	for i, t := range ts.tags {
+		rv[i] = string(t.Bytes())
 	}
+	return rv
 }
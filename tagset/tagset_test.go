@@ -1,6 +1,7 @@
 package tagset
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/djmitche/tagset/ident"
@@ -27,4 +28,98 @@ func TestTagsetAccessors(t *testing.T) {
 	assert.Equal(t, []byte("x:abc"), ser)
 }
 
-// TODO: test `has`, `forEach` if they still exist
+func TestTagsetLen(t *testing.T) {
+	ts := tagsetOf("a", "b", "c")
+	assert.Equal(t, 3, ts.Len())
+	assert.Equal(t, 0, emptyTagSet.Len())
+}
+
+func TestTagsetHas(t *testing.T) {
+	ts := tagsetOf("a", "b", "c")
+	assert.True(t, ts.Has(idFoundry.Ident([]byte("a"))))
+	assert.True(t, ts.Has(idFoundry.Ident([]byte("c"))))
+	assert.False(t, ts.Has(idFoundry.Ident([]byte("d"))))
+	assert.False(t, emptyTagSet.Has(idFoundry.Ident([]byte("a"))))
+
+	assert.True(t, ts.HasBytes([]byte("b")))
+	assert.False(t, ts.HasBytes([]byte("x")))
+}
+
+// manyIdents returns n distinct idents
+func manyIdents(n int) []ident.Ident {
+	idents := make([]ident.Ident, n)
+	for i := range idents {
+		idents[i] = idFoundry.Ident([]byte(fmt.Sprintf("tag:%d", i)))
+	}
+	return idents
+}
+
+func TestTagsetHasMany(t *testing.T) {
+	for _, n := range []int{1, 2, 7, 100, 1000} {
+		t.Run(fmt.Sprintf("n=%d", n), func(t *testing.T) {
+			idents := manyIdents(n)
+			ts := (&NullFoundry{}).NewWithoutDuplicates(append([]ident.Ident{}, idents...))
+
+			for _, id := range idents {
+				assert.True(t, ts.Has(id))
+			}
+			for i := n; i < n+100; i++ {
+				assert.False(t, ts.HasBytes([]byte(fmt.Sprintf("tag:%d", i))))
+			}
+		})
+	}
+}
+
+func TestTagsetForEach(t *testing.T) {
+	ts := tagsetOf("a", "b", "c")
+
+	seen := []string{}
+	ts.ForEach(func(tag ident.Ident) bool {
+		seen = append(seen, string(tag.Bytes()))
+		return true
+	})
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, seen)
+
+	// stops early
+	count := 0
+	ts.ForEach(func(tag ident.Ident) bool {
+		count++
+		return false
+	})
+	assert.Equal(t, 1, count)
+}
+
+func TestTagsetAppendTags(t *testing.T) {
+	ts := tagsetOf("a", "b")
+	x := idFoundry.Ident([]byte("x"))
+
+	tags := ts.AppendTags([]ident.Ident{x})
+	assert.Len(t, tags, 3)
+	assert.True(t, tags[0].Equals(x))
+
+	// modifying the result does not modify the tagset
+	tags[1] = x
+	assert.False(t, ts.Has(x))
+}
+
+func TestTagsetStrings(t *testing.T) {
+	ts := tagsetOf("a", "b", "c")
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, ts.Strings())
+	assert.Equal(t, []string{}, emptyTagSet.Strings())
+}
+
+func BenchmarkHas(b *testing.B) {
+	for _, n := range []int{4, 32, 256} {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			idents := manyIdents(n)
+			ts := (&NullFoundry{}).NewWithoutDuplicates(append([]ident.Ident{}, idents...))
+			absent := idFoundry.Ident([]byte("absent"))
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				ts.Has(idents[i%n])
+				ts.Has(absent)
+			}
+		})
+	}
+}
@@ -0,0 +1,616 @@
+// The `tagsettest` package provides a conformance test suite for
+// implementations of `tagset.Foundry`.  To validate a foundry, run the suite
+// with a function that creates new instances of it:
+//
+//	func TestMyFoundry(t *testing.T) {
+//		suite.Run(t, &tagsettest.FoundrySuite{
+//			NewFoundry: func() tagset.Foundry { return NewMyFoundry() },
+//		})
+//	}
+package tagsettest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"github.com/djmitche/tagset/ident"
+	"github.com/djmitche/tagset/tagset"
+	"github.com/stretchr/testify/suite"
+	"github.com/twmb/murmur3"
+)
+
+// A "base" on which to create test suites for foundries.  This puts a foundry through
+// its paces for the behavior defined by the interface, and for the optional guarantees
+// described by its fields.  Foundry-specific tests should occur in the test suite for
+// that foundry, which may embed this one.
+type FoundrySuite struct {
+	suite.Suite
+
+	// NewFoundry creates a new, empty foundry.  It is called before each
+	// test.
+	NewFoundry func() tagset.Foundry
+
+	// Interns is true if the foundry returns the same TagSet when parsing
+	// the same bytes repeatedly.
+	Interns bool
+
+	// InternsContent is true if the foundry returns the same TagSet for any
+	// operation producing the same set of tags.
+	InternsContent bool
+
+	// Threadsafe is true if the foundry may be used concurrently from
+	// multiple goroutines.
+	Threadsafe bool
+
+	f tagset.Foundry
+}
+
+func (s *FoundrySuite) SetupTest() {
+	s.f = s.NewFoundry()
+}
+
+// idFoundry is used to generate tags for tests
+var idFoundry = ident.Must(ident.NewThreadsafeFoundry(ident.Must(ident.NewInternFoundry())))
+
+func hashOf(tags ...string) (uint64, uint64) {
+	var hh, hl uint64
+	for _, t := range tags {
+		thh, thl := murmur3.Sum128([]byte(t))
+		hh ^= thh
+		hl ^= thl
+	}
+	return hh, hl
+}
+
+// serializationOf returns the expected serialization of a TagSet containing
+// the given distinct tags, which are sorted by hash.
+func serializationOf(tags ...string) []byte {
+	idents := make([]ident.Ident, len(tags))
+	for i, t := range tags {
+		idents[i] = idFoundry.Ident([]byte(t))
+	}
+	ident.Sort(idents)
+
+	bufs := make([][]byte, len(idents))
+	for i, id := range idents {
+		bufs[i] = id.Bytes()
+	}
+	return bytes.Join(bufs, []byte(","))
+}
+
+// requireSorted asserts that the tags in the TagSet, and its serialization,
+// are in hash order.
+func (s *FoundrySuite) requireSorted(ts *tagset.TagSet) {
+	tags := ts.AppendTags(nil)
+	for i := 1; i < len(tags); i++ {
+		s.Require().True(tags[i-1].Less(tags[i]), "tags not sorted: %v", ts.Strings())
+	}
+	s.Require().Equal(serializationOf(ts.Strings()...), ts.Serialization())
+}
+
+func (s *FoundrySuite) TestEmptyHash() {
+	tg := idFoundry.Ident([]byte("x:abc"))
+	ts := s.f.NewWithoutDuplicates([]ident.Ident{tg})
+	expH, expL := hashOf("x:abc")
+	gotH, gotL := ts.Hash()
+	s.Equal(expH, gotH)
+	s.Equal(expL, gotL)
+}
+
+func (s *FoundrySuite) TestSingleTagHash() {
+	tg := idFoundry.Ident([]byte("x:abc"))
+	ts := s.f.NewWithoutDuplicates([]ident.Ident{tg})
+	expH, expL := hashOf("x:abc")
+	gotH, gotL := ts.Hash()
+	s.Equal(expH, gotH)
+	s.Equal(expL, gotL)
+}
+
+func (s *FoundrySuite) TestParseEmpty() {
+	ts := s.f.Parse(idFoundry, []byte{})
+	s.Equal(uint64(0), ts.HashL())
+	s.Equal(uint64(0), ts.HashH())
+	s.Equal([]byte{}, ts.Serialization())
+}
+
+func (s *FoundrySuite) TestParseSingle() {
+	ts := s.f.Parse(idFoundry, []byte("abc:def"))
+	expH, expL := hashOf("abc:def")
+	gotH, gotL := ts.Hash()
+	s.Equal(expH, gotH)
+	s.Equal(expL, gotL)
+	s.Equal([]byte("abc:def"), ts.Serialization())
+}
+
+func (s *FoundrySuite) TestParseMulti() {
+	ts := s.f.Parse(idFoundry, []byte("a,b,c"))
+	expH, expL := hashOf("a", "b", "c")
+	gotH, gotL := ts.Hash()
+	s.Equal(expH, gotH)
+	s.Equal(expL, gotL)
+	// tags are serialized in hash order
+	s.Equal(serializationOf("a", "b", "c"), ts.Serialization())
+}
+
+func (s *FoundrySuite) TestParseMultiDupes() {
+	ts := s.f.Parse(idFoundry, []byte("a,b,a,b,c,c"))
+	expH, expL := hashOf("a", "b", "c")
+	gotH, gotL := ts.Hash()
+	s.Equal(expH, gotH)
+	s.Equal(expL, gotL)
+	// tags are serialized in hash order
+	s.Equal(serializationOf("a", "b", "c"), ts.Serialization())
+}
+
+func (s *FoundrySuite) TestParseChecked() {
+	ts, err := s.f.ParseChecked(idFoundry, []byte("a,b,c"))
+	s.NoError(err)
+	s.Equal(serializationOf("a", "b", "c"), ts.Serialization())
+
+	ts, err = s.f.ParseChecked(idFoundry, []byte{})
+	s.NoError(err)
+	s.Equal(0, ts.Len())
+
+	ts, err = s.f.ParseChecked(idFoundry, []byte("a,,b,\xff"))
+	s.Nil(ts)
+	var perr *tagset.ParseError
+	s.Require().True(errors.As(err, &perr))
+	s.False(perr.Truncated)
+	s.Equal([]tagset.ParseProblem{
+		{Kind: tagset.EmptyTag, Index: 1, Length: 0},
+		{Kind: tagset.InvalidUTF8, Index: 3, Length: 1},
+	}, perr.Problems)
+}
+
+func (s *FoundrySuite) TestFromBytes() {
+	tg1 := idFoundry.Ident([]byte("x:abc"))
+	tg2 := idFoundry.Ident([]byte("y:def"))
+	ts := s.f.NewWithoutDuplicates([]ident.Ident{tg1, tg2})
+	expH, expL := hashOf("x:abc", "y:def")
+	gotH, gotL := ts.Hash()
+	s.Equal(expH, gotH)
+	s.Equal(expL, gotL)
+}
+
+func (s *FoundrySuite) TestTwoTagHash() {
+	tg1 := idFoundry.Ident([]byte("x:abc"))
+	tg2 := idFoundry.Ident([]byte("y:def"))
+
+	expH, expL := hashOf("x:abc", "y:def")
+
+	// hash should be the same regardless of order
+	ts12 := s.f.NewWithoutDuplicates([]ident.Ident{tg1, tg2})
+	got12H, got12L := ts12.Hash()
+	s.Equal(expH, got12H)
+	s.Equal(expL, got12L)
+
+	ts21 := s.f.NewWithoutDuplicates([]ident.Ident{tg2, tg1})
+	got21H, got21L := ts21.Hash()
+	s.Equal(expH, got21H)
+	s.Equal(expL, got21L)
+}
+
+func (s *FoundrySuite) TestSimpleDisjointUnions() {
+	tg1 := idFoundry.Ident([]byte("w:mno"))
+	ts1 := s.f.NewWithoutDuplicates([]ident.Ident{tg1})
+	tg2 := idFoundry.Ident([]byte("x:abc"))
+	ts2 := s.f.NewWithoutDuplicates([]ident.Ident{tg2})
+
+	expH, expL := hashOf("w:mno", "x:abc")
+
+	u := s.f.Union(ts1, ts2)
+	gotH, gotL := u.Hash()
+
+	s.Equal(expH, gotH)
+	s.Equal(expL, gotL)
+}
+
+func (s *FoundrySuite) TestDisjointUnions() {
+	test := func(union func(t1 *tagset.TagSet, t2 *tagset.TagSet) *tagset.TagSet) func() {
+		return func() {
+			tg1 := idFoundry.Ident([]byte("w:mno"))
+			ts1 := s.f.NewWithoutDuplicates([]ident.Ident{tg1})
+			tg2 := idFoundry.Ident([]byte("x:abc"))
+			ts2 := s.f.NewWithoutDuplicates([]ident.Ident{tg2})
+			tg3 := idFoundry.Ident([]byte("y:def"))
+			tg4 := idFoundry.Ident([]byte("z:jkl"))
+			ts3 := s.f.NewWithoutDuplicates([]ident.Ident{tg3, tg4})
+
+			expH, expL := hashOf("w:mno", "x:abc", "y:def", "z:jkl")
+
+			// hash should be commutative and associative, so try a bunch
+			// of combinations
+			check := func(unionedTs *tagset.TagSet) {
+				s.Equal(expH, unionedTs.HashH(), "H")
+				s.Equal(expL, unionedTs.HashL(), "L")
+			}
+			check(union(ts1, union(ts2, ts3)))
+			check(union(ts1, union(ts3, ts2)))
+			check(union(union(ts2, ts3), ts1))
+			check(union(union(ts3, ts2), ts1))
+			check(union(ts3, union(ts1, ts2)))
+			check(union(ts3, union(ts2, ts1)))
+			check(union(union(ts1, ts2), ts3))
+			check(union(union(ts2, ts1), ts3))
+		}
+	}
+	s.Run("Union",
+		test(func(t1 *tagset.TagSet, t2 *tagset.TagSet) *tagset.TagSet {
+			return s.f.Union(t1, t2)
+		}))
+	s.Run("DisjointUnion",
+		test(func(t1 *tagset.TagSet, t2 *tagset.TagSet) *tagset.TagSet {
+			return s.f.DisjointUnion(t1, t2)
+		}))
+}
+
+func (s *FoundrySuite) TestUnionOverlappingHashes() {
+	test := func(ts1 *tagset.TagSet, ts2 *tagset.TagSet, ts3 *tagset.TagSet, expH, expL uint64) func() {
+		return func() {
+			check := func(unionedTs *tagset.TagSet) {
+				s.Equal(expH, unionedTs.HashH())
+				s.Equal(expL, unionedTs.HashL())
+			}
+
+			// hash should be commutative and associative, so try a bunch
+			// of combinations
+			check(s.f.Union(ts1, s.f.Union(ts2, ts3)))
+			check(s.f.Union(ts1, s.f.Union(ts3, ts2)))
+			check(s.f.Union(s.f.Union(ts2, ts3), ts1))
+			check(s.f.Union(s.f.Union(ts3, ts2), ts1))
+			check(s.f.Union(ts3, s.f.Union(ts1, ts2)))
+			check(s.f.Union(ts3, s.f.Union(ts2, ts1)))
+			check(s.f.Union(s.f.Union(ts1, ts2), ts3))
+			check(s.f.Union(s.f.Union(ts2, ts1), ts3))
+		}
+	}
+
+	r := rand.New(rand.NewSource(13))
+
+	// choose a random slice of vals.  It might be empty!
+	chooseSubslice := func(vals []byte) []byte {
+		a := r.Intn(len(vals))
+		b := r.Intn(len(vals)-a) + a
+		return vals[a:b]
+	}
+
+	bytesToTagSet := func(bytes []byte) *tagset.TagSet {
+		tags := []ident.Ident{}
+		for _, b := range bytes {
+			tags = append(tags, idFoundry.Ident([]byte{b}))
+		}
+		return s.f.NewWithDuplicates(tags)
+	}
+
+	letters := []byte("abcdefghijklmnopqrstuvwxyz")
+	for i := 0; i < 100; i++ {
+		slice1 := chooseSubslice(letters)
+		ts1 := bytesToTagSet(slice1)
+		slice2 := chooseSubslice(letters)
+		ts2 := bytesToTagSet(slice2)
+		slice3 := chooseSubslice(letters)
+		ts3 := bytesToTagSet(slice3)
+
+		// do a manual union of those slices
+		seen := map[byte]struct{}{}
+		for _, b := range slice1 {
+			seen[b] = struct{}{}
+		}
+		for _, b := range slice2 {
+			seen[b] = struct{}{}
+		}
+		for _, b := range slice3 {
+			seen[b] = struct{}{}
+		}
+
+		// and compute the hash of that union
+		var expH, expL uint64
+		for b := range seen {
+			h, l := murmur3.Sum128([]byte{b})
+			expH ^= h
+			expL ^= l
+		}
+
+		s.Run(fmt.Sprintf("%d: %s %s %s", i, slice1, slice2, slice3), test(ts1, ts2, ts3, expH, expL))
+	}
+}
+
+// The caller may reuse the buffer passed to Parse, without affecting the
+// returned TagSet.
+func (s *FoundrySuite) TestParseBufferReuse() {
+	buf := []byte("a:1,b:2")
+	ts := s.f.Parse(idFoundry, buf)
+	copy(buf, "c:3,d:4")
+
+	expH, expL := hashOf("a:1", "b:2")
+	s.Equal(expH, ts.HashH())
+	s.Equal(expL, ts.HashL())
+	s.Contains([][]byte{[]byte("a:1,b:2"), []byte("b:2,a:1")}, ts.Serialization())
+
+	ts2 := s.f.Parse(idFoundry, buf)
+	expH, expL = hashOf("c:3", "d:4")
+	s.Equal(expH, ts2.HashH())
+	s.Equal(expL, ts2.HashL())
+}
+
+// Unions do not modify their inputs.
+func (s *FoundrySuite) TestUnionImmutable() {
+	ts1 := s.f.Parse(idFoundry, []byte("a,b"))
+	ts2 := s.f.Parse(idFoundry, []byte("b,c"))
+	ser1 := append([]byte{}, ts1.Serialization()...)
+	ser2 := append([]byte{}, ts2.Serialization()...)
+
+	s.f.Union(ts1, ts2)
+	s.f.DisjointUnion(ts1, s.f.Parse(idFoundry, []byte("d")))
+
+	expH, expL := hashOf("a", "b")
+	s.Equal(expH, ts1.HashH())
+	s.Equal(expL, ts1.HashL())
+	s.Equal(ser1, ts1.Serialization())
+	expH, expL = hashOf("b", "c")
+	s.Equal(expH, ts2.HashH())
+	s.Equal(expL, ts2.HashL())
+	s.Equal(ser2, ts2.Serialization())
+}
+
+func (s *FoundrySuite) TestInterning() {
+	if !s.Interns {
+		s.T().Skip("foundry does not intern")
+	}
+
+	ts1 := s.f.Parse(idFoundry, []byte("a,b,c"))
+	ts2 := s.f.Parse(idFoundry, []byte("a,b,c"))
+	s.True(ts1 == ts2, "repeated parses return the same TagSet")
+}
+
+// Foundries that intern by content return the same TagSet for the same tags,
+// however it was created.
+func (s *FoundrySuite) TestContentInterning() {
+	if !s.InternsContent {
+		s.T().Skip("foundry does not intern by content")
+	}
+
+	abc := s.f.Parse(idFoundry, []byte("a,b,c"))
+	s.True(abc == s.f.Parse(idFoundry, []byte("c,b,a")), "parses of the same tags")
+	s.True(abc == s.f.Parse(idFoundry, []byte("a,b,c,a")), "parses with duplicates")
+
+	a := idFoundry.Ident([]byte("a"))
+	b := idFoundry.Ident([]byte("b"))
+	c := idFoundry.Ident([]byte("c"))
+	s.True(abc == s.f.NewWithDuplicates([]ident.Ident{c, a, b, a}), "NewWithDuplicates")
+	s.True(abc == s.f.NewWithoutDuplicates([]ident.Ident{b, c, a}), "NewWithoutDuplicates")
+
+	ab := s.f.Parse(idFoundry, []byte("a,b"))
+	bc := s.f.Parse(idFoundry, []byte("b,c"))
+	s.True(abc == s.f.Union(ab, bc), "Union")
+	s.True(abc == s.f.UnionN(ab, bc, abc), "UnionN")
+	s.True(ab == s.f.Without(abc, c), "Without")
+	s.True(bc == s.f.Difference(abc, s.f.Parse(idFoundry, []byte("a"))), "Difference")
+}
+
+// TagSets are immutable and may be shared between goroutines, regardless
+// of whether the foundry is threadsafe.
+func (s *FoundrySuite) TestConcurrentSerialization() {
+	const workers = 8
+	ts := s.f.Union(
+		s.f.Parse(idFoundry, []byte("a,b,c")),
+		s.f.Parse(idFoundry, []byte("c,d,e")))
+	exp := serializationOf("a", "b", "c", "d", "e")
+
+	results := make([][]byte, workers)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func(w int) {
+			defer wg.Done()
+			results[w] = ts.Serialization()
+		}(w)
+	}
+	wg.Wait()
+
+	for _, r := range results {
+		s.Equal(exp, r)
+	}
+}
+
+func (s *FoundrySuite) TestConcurrent() {
+	if !s.Threadsafe {
+		s.T().Skip("foundry is not threadsafe")
+	}
+
+	const workers = 8
+	const count = 1000
+	expH, expL := hashOf("a", "b", "c", "d")
+
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				ts1 := s.f.Parse(idFoundry, []byte(fmt.Sprintf("a,b,x:%d", i%10)))
+				ts2 := s.f.Parse(idFoundry, []byte("c,d"))
+				u := s.f.Union(ts1, ts2)
+				h, l := hashOf(fmt.Sprintf("x:%d", i%10))
+				s.Equal(expH^h, u.HashH())
+				s.Equal(expL^l, u.HashL())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *FoundrySuite) TestAccessors() {
+	ts := s.f.Parse(idFoundry, []byte("a,b,a,c"))
+	s.Equal(3, ts.Len())
+	s.ElementsMatch([]string{"a", "b", "c"}, ts.Strings())
+	s.True(ts.Has(idFoundry.Ident([]byte("b"))))
+	s.False(ts.Has(idFoundry.Ident([]byte("d"))))
+	s.True(ts.HasBytes([]byte("c")))
+	s.False(ts.HasBytes([]byte("d")))
+	s.Len(ts.AppendTags(nil), 3)
+
+	u := s.f.Union(ts, s.f.Parse(idFoundry, []byte("c,d")))
+	s.Equal(4, u.Len())
+	s.ElementsMatch([]string{"a", "b", "c", "d"}, u.Strings())
+
+	empty := s.f.Parse(idFoundry, []byte{})
+	s.Equal(0, empty.Len())
+	s.False(empty.HasBytes([]byte("a")))
+}
+
+func (s *FoundrySuite) TestSorted() {
+	var tags []ident.Ident
+	for i := 0; i < 50; i++ {
+		tags = append(tags, idFoundry.Ident([]byte(fmt.Sprintf("tag:%d", i))))
+	}
+
+	s.requireSorted(s.f.Parse(idFoundry, []byte("x,y,z,a,b,c,a")))
+	s.requireSorted(s.f.NewWithDuplicates(append([]ident.Ident{}, tags...)))
+	s.requireSorted(s.f.NewWithoutDuplicates(append([]ident.Ident{}, tags...)))
+
+	ts1 := s.f.NewWithoutDuplicates(append([]ident.Ident{}, tags[:30]...))
+	ts2 := s.f.NewWithoutDuplicates(append([]ident.Ident{}, tags[20:]...))
+	ts3 := s.f.NewWithoutDuplicates(append([]ident.Ident{}, tags[30:]...))
+
+	u := s.f.Union(ts1, ts2)
+	s.requireSorted(u)
+	s.Equal(50, u.Len())
+
+	d := s.f.DisjointUnion(ts3, ts1)
+	s.requireSorted(d)
+	s.Equal(50, d.Len())
+	s.Equal(u.Serialization(), d.Serialization())
+}
+
+// requireTags asserts that the TagSet contains exactly the given tags, with
+// the correct hash, and is sorted.
+func (s *FoundrySuite) requireTags(ts *tagset.TagSet, tags ...string) {
+	s.Require().ElementsMatch(tags, ts.Strings())
+	expH, expL := hashOf(tags...)
+	s.Require().Equal(expH, ts.HashH())
+	s.Require().Equal(expL, ts.HashL())
+	s.requireSorted(ts)
+}
+
+func (s *FoundrySuite) TestIntersect() {
+	ts1 := s.f.Parse(idFoundry, []byte("a,b,c,d"))
+	ts2 := s.f.Parse(idFoundry, []byte("c,d,e"))
+	empty := s.f.Parse(idFoundry, []byte{})
+
+	s.requireTags(s.f.Intersect(ts1, ts2), "c", "d")
+	s.requireTags(s.f.Intersect(ts2, ts1), "c", "d")
+	s.requireTags(s.f.Intersect(ts1, ts1), "a", "b", "c", "d")
+	s.requireTags(s.f.Intersect(ts1, empty))
+	s.requireTags(s.f.Intersect(empty, ts1))
+	s.requireTags(s.f.Intersect(ts1, s.f.Parse(idFoundry, []byte("x,y"))))
+
+	// inputs are unchanged
+	s.requireTags(ts1, "a", "b", "c", "d")
+	s.requireTags(ts2, "c", "d", "e")
+}
+
+func (s *FoundrySuite) TestDifference() {
+	ts1 := s.f.Parse(idFoundry, []byte("a,b,c,d"))
+	ts2 := s.f.Parse(idFoundry, []byte("c,d,e"))
+	empty := s.f.Parse(idFoundry, []byte{})
+
+	s.requireTags(s.f.Difference(ts1, ts2), "a", "b")
+	s.requireTags(s.f.Difference(ts2, ts1), "e")
+	s.requireTags(s.f.Difference(ts1, ts1))
+	s.requireTags(s.f.Difference(ts1, empty), "a", "b", "c", "d")
+	s.requireTags(s.f.Difference(empty, ts1))
+
+	// inputs are unchanged
+	s.requireTags(ts1, "a", "b", "c", "d")
+	s.requireTags(ts2, "c", "d", "e")
+}
+
+func (s *FoundrySuite) TestWithout() {
+	ts := s.f.Parse(idFoundry, []byte("host:a,env:prod,service:x"))
+	host := idFoundry.Ident([]byte("host:a"))
+	service := idFoundry.Ident([]byte("service:x"))
+	other := idFoundry.Ident([]byte("other"))
+
+	s.requireTags(s.f.Without(ts, host), "env:prod", "service:x")
+	s.requireTags(s.f.Without(ts, service, other, host), "env:prod")
+	s.requireTags(s.f.Without(ts, other), "host:a", "env:prod", "service:x")
+	s.requireTags(s.f.Without(ts), "host:a", "env:prod", "service:x")
+
+	// the argument slice is not modified
+	tags := []ident.Ident{service, other, host}
+	s.f.Without(ts, tags...)
+	s.Equal([]ident.Ident{service, other, host}, tags)
+}
+
+func (s *FoundrySuite) TestFilter() {
+	ts := s.f.Parse(idFoundry, []byte("host:a,env:prod,host:b,service:x"))
+	notHost := func(t ident.Ident) bool { return !bytes.HasPrefix(t.Bytes(), []byte("host:")) }
+
+	s.requireTags(s.f.Filter(ts, notHost), "env:prod", "service:x")
+	s.requireTags(s.f.Filter(ts, func(ident.Ident) bool { return true }), "host:a", "env:prod", "host:b", "service:x")
+	s.requireTags(s.f.Filter(ts, func(ident.Ident) bool { return false }))
+}
+
+func (s *FoundrySuite) TestOverride() {
+	base := s.f.Parse(idFoundry, []byte("service:web,env:prod,team:a,team:b,bare"))
+	empty := s.f.Parse(idFoundry, []byte{})
+
+	s.requireTags(s.f.Override(base, s.f.Parse(idFoundry, []byte("service:api"))),
+		"service:api", "env:prod", "team:a", "team:b", "bare")
+	s.requireTags(s.f.Override(base, s.f.Parse(idFoundry, []byte("team:c,new:x"))),
+		"service:web", "env:prod", "team:c", "new:x", "bare")
+	s.requireTags(s.f.Override(base, s.f.Parse(idFoundry, []byte("env:prod"))),
+		"service:web", "env:prod", "team:a", "team:b", "bare")
+	s.requireTags(s.f.Override(base, empty),
+		"service:web", "env:prod", "team:a", "team:b", "bare")
+	s.requireTags(s.f.Override(empty, base),
+		"service:web", "env:prod", "team:a", "team:b", "bare")
+
+	// inputs are unchanged
+	s.requireTags(base, "service:web", "env:prod", "team:a", "team:b", "bare")
+}
+
+func (s *FoundrySuite) TestUnionN() {
+	host := s.f.Parse(idFoundry, []byte("host:a,env:prod"))
+	sample := s.f.Parse(idFoundry, []byte("env:prod,path:/x"))
+	tagger := s.f.Parse(idFoundry, []byte("team:a,host:a,service:web"))
+	empty := s.f.Parse(idFoundry, []byte{})
+
+	u := s.f.UnionN(host, sample, tagger)
+	s.requireTags(u, "host:a", "env:prod", "path:/x", "team:a", "service:web")
+	s.Equal(s.f.Union(s.f.Union(host, sample), tagger).Serialization(), u.Serialization())
+
+	s.requireTags(s.f.UnionN(host, empty, host, empty), "host:a", "env:prod")
+	s.requireTags(s.f.UnionN(empty, host), "host:a", "env:prod")
+	s.requireTags(s.f.UnionN(host), "host:a", "env:prod")
+	s.requireTags(s.f.UnionN(empty, empty))
+	s.requireTags(s.f.UnionN())
+
+	// more sets than fit in the stack buffer
+	var sets []*tagset.TagSet
+	var all []string
+	for i := 0; i < 20; i++ {
+		tag := fmt.Sprintf("t:%d", i)
+		sets = append(sets, s.f.Parse(idFoundry, []byte(tag+",common")))
+		all = append(all, tag)
+	}
+	s.requireTags(s.f.UnionN(sets...), append(all, "common")...)
+}
+
+func (s *FoundrySuite) TestDisjointUnionN() {
+	ts1 := s.f.Parse(idFoundry, []byte("a,b"))
+	ts2 := s.f.Parse(idFoundry, []byte("c"))
+	ts3 := s.f.Parse(idFoundry, []byte("d,e,f"))
+	empty := s.f.Parse(idFoundry, []byte{})
+
+	s.requireTags(s.f.DisjointUnionN(ts1, ts2, empty, ts3), "a", "b", "c", "d", "e", "f")
+	s.requireTags(s.f.DisjointUnionN(ts2), "c")
+	s.requireTags(s.f.DisjointUnionN())
+
+	// inputs are unchanged
+	s.requireTags(ts1, "a", "b")
+}
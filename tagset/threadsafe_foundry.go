@@ -0,0 +1,110 @@
+package tagset
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/djmitche/tagset/ident"
+)
+
+// A ThreadsafeFoundry wraps another Foundry and applies locking to allow
+// concurrent access from multiple goroutines.  All operations are serialized
+// by a single mutex; see ShardedFoundry for a foundry with less contention.
+//
+// The ident.Foundry passed to Parse must also be threadsafe.
+type ThreadsafeFoundry struct {
+	sync.Mutex
+	inner Foundry
+}
+
+// Create a ThreadsafeFoundry wrapping the given foundry, which must not be
+// used directly after this call.
+func NewThreadsafeFoundry(inner Foundry) (*ThreadsafeFoundry, error) {
+	if inner == nil {
+		return nil, errors.New("inner foundry must not be nil")
+	}
+	return &ThreadsafeFoundry{
+		sync.Mutex{},
+		inner,
+	}, nil
+}
+
+func (f *ThreadsafeFoundry) NewWithDuplicates(tags []ident.Ident) *TagSet {
+	f.Lock()
+	defer f.Unlock()
+	return f.inner.NewWithDuplicates(tags)
+}
+
+func (f *ThreadsafeFoundry) NewWithoutDuplicates(tags []ident.Ident) *TagSet {
+	f.Lock()
+	defer f.Unlock()
+	return f.inner.NewWithoutDuplicates(tags)
+}
+
+func (f *ThreadsafeFoundry) Parse(foundry ident.Foundry, rawTags []byte) *TagSet {
+	f.Lock()
+	defer f.Unlock()
+	return f.inner.Parse(foundry, rawTags)
+}
+
+func (f *ThreadsafeFoundry) ParseChecked(foundry ident.Foundry, rawTags []byte) (*TagSet, error) {
+	f.Lock()
+	defer f.Unlock()
+	return f.inner.ParseChecked(foundry, rawTags)
+}
+
+func (f *ThreadsafeFoundry) Union(ts1 *TagSet, ts2 *TagSet) *TagSet {
+	f.Lock()
+	defer f.Unlock()
+	return f.inner.Union(ts1, ts2)
+}
+
+func (f *ThreadsafeFoundry) DisjointUnion(ts1 *TagSet, ts2 *TagSet) *TagSet {
+	f.Lock()
+	defer f.Unlock()
+	return f.inner.DisjointUnion(ts1, ts2)
+}
+
+func (f *ThreadsafeFoundry) UnionN(sets ...*TagSet) *TagSet {
+	f.Lock()
+	defer f.Unlock()
+	return f.inner.UnionN(sets...)
+}
+
+func (f *ThreadsafeFoundry) DisjointUnionN(sets ...*TagSet) *TagSet {
+	f.Lock()
+	defer f.Unlock()
+	return f.inner.DisjointUnionN(sets...)
+}
+
+func (f *ThreadsafeFoundry) Override(base *TagSet, overrides *TagSet) *TagSet {
+	f.Lock()
+	defer f.Unlock()
+	return f.inner.Override(base, overrides)
+}
+
+func (f *ThreadsafeFoundry) Intersect(ts1 *TagSet, ts2 *TagSet) *TagSet {
+	f.Lock()
+	defer f.Unlock()
+	return f.inner.Intersect(ts1, ts2)
+}
+
+func (f *ThreadsafeFoundry) Difference(ts1 *TagSet, ts2 *TagSet) *TagSet {
+	f.Lock()
+	defer f.Unlock()
+	return f.inner.Difference(ts1, ts2)
+}
+
+func (f *ThreadsafeFoundry) Without(ts *TagSet, tags ...ident.Ident) *TagSet {
+	f.Lock()
+	defer f.Unlock()
+	return f.inner.Without(ts, tags...)
+}
+
+// Filter filters a TagSet as for the inner foundry.  The lock is held while
+// `keep` is called, so it must not call this foundry.
+func (f *ThreadsafeFoundry) Filter(ts *TagSet, keep func(ident.Ident) bool) *TagSet {
+	f.Lock()
+	defer f.Unlock()
+	return f.inner.Filter(ts, keep)
+}
@@ -0,0 +1,73 @@
+package tagset_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/djmitche/tagset/ident"
+	"github.com/djmitche/tagset/tagset"
+	"github.com/djmitche/tagset/tagset/tagsettest"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type ThreadsafeFoundrySuite struct {
+	tagsettest.FoundrySuite
+}
+
+func TestThreadsafeFoundry(t *testing.T) {
+	suite.Run(t, &ThreadsafeFoundrySuite{
+		FoundrySuite: tagsettest.FoundrySuite{
+			NewFoundry: func() tagset.Foundry {
+				return tagset.Must(tagset.NewThreadsafeFoundry(tagset.Must(tagset.NewInternFoundry())))
+			},
+			Interns:    true,
+			Threadsafe: true,
+		},
+	})
+}
+
+func TestThreadsafeRevolvingFoundry(t *testing.T) {
+	suite.Run(t, &ThreadsafeFoundrySuite{
+		FoundrySuite: tagsettest.FoundrySuite{
+			NewFoundry: func() tagset.Foundry {
+				return tagset.Must(tagset.NewThreadsafeFoundry(tagset.Must(tagset.NewRevolvingFoundry(tagset.WithRotateAfter(100)))))
+			},
+			Interns:    true,
+			Threadsafe: true,
+		},
+	})
+}
+
+func TestThreadsafeFoundryNil(t *testing.T) {
+	_, err := tagset.NewThreadsafeFoundry(nil)
+	require.Error(t, err)
+}
+
+func TestThreadsafeFoundryCounters(t *testing.T) {
+	idFoundry := ident.Must(ident.NewThreadsafeFoundry(ident.Must(ident.NewInternFoundry())))
+	inner := tagset.Must(tagset.NewInternFoundry()).(*tagset.InternFoundry)
+	f, err := tagset.NewThreadsafeFoundry(inner)
+	require.NoError(t, err)
+
+	const workers = 8
+	const count = 1000
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				ts := f.Parse(idFoundry, []byte(fmt.Sprintf("x:%d,y", i%100)))
+				f.Union(ts, f.Parse(idFoundry, []byte("z")))
+			}
+		}()
+	}
+	wg.Wait()
+
+	f.Lock()
+	defer f.Unlock()
+	require.Equal(t, uint64(workers*count*2), inner.Parses)
+	require.Equal(t, uint64(101), inner.ParseMisses)
+}
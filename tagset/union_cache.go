@@ -0,0 +1,75 @@
+package tagset
+
+import (
+	"encoding/binary"
+
+	"github.com/twmb/murmur3"
+)
+
+// A unionCache memoizes the results of unions, keyed by the hashes of the two
+// input TagSets.  Its size is bounded by keeping two generations of
+// tagsetTables: when the current generation is full, it replaces the previous
+// generation, and a new, empty current generation is started.  Hits in the
+// previous generation are promoted to the current generation, so frequently
+// used unions survive rotation.
+//
+// A unionCache is not threadsafe.
+type unionCache struct {
+	// maxSize is the number of entries in each generation
+	maxSize int
+
+	cur, prev *tagsetTable
+}
+
+// Create a unionCache holding at least maxSize entries, and at most twice
+// that.
+func newUnionCache(maxSize int) *unionCache {
+	return &unionCache{
+		maxSize: maxSize,
+		cur:     newTagsetTable(maxSize),
+		prev:    newTagsetTable(0),
+	}
+}
+
+// unionKey calculates the key for the union of two TagSets.  Union is
+// commutative, so the key is independent of the order of the inputs.  XOR is
+// not suitable for combining the hashes, as different pairs of inputs with
+// different unions may have the same XOR.
+func unionKey(ts1 *TagSet, ts2 *TagSet) (uint64, uint64) {
+	if ts2.hashH < ts1.hashH || (ts2.hashH == ts1.hashH && ts2.hashL < ts1.hashL) {
+		ts1, ts2 = ts2, ts1
+	}
+	var buf [32]byte
+	binary.LittleEndian.PutUint64(buf[0:], ts1.hashH)
+	binary.LittleEndian.PutUint64(buf[8:], ts1.hashL)
+	binary.LittleEndian.PutUint64(buf[16:], ts2.hashH)
+	binary.LittleEndian.PutUint64(buf[24:], ts2.hashL)
+	return murmur3.Sum128(buf[:])
+}
+
+// get returns the cached union with the given key, or nil
+func (c *unionCache) get(keyH, keyL uint64) *TagSet {
+	if ts := c.cur.get(keyH, keyL); ts != nil {
+		return ts
+	}
+	if ts := c.prev.get(keyH, keyL); ts != nil {
+		c.insert(keyH, keyL, ts)
+		return ts
+	}
+	return nil
+}
+
+// insert adds a union with the given key, rotating generations if necessary
+func (c *unionCache) insert(keyH, keyL uint64, ts *TagSet) {
+	if c.cur.len() >= c.maxSize {
+		c.prev = c.cur
+		c.cur = newTagsetTable(c.maxSize)
+	}
+	c.cur.insert(keyH, keyL, ts)
+}
+
+// len returns the number of entries in the cache, including any duplicated
+// between generations
+func (c *unionCache) len() int {
+	return c.cur.len() + c.prev.len()
+}
@@ -0,0 +1,102 @@
+package tagset
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnionKey(t *testing.T) {
+	xy, z := tagsetOf("x", "y"), tagsetOf("z")
+	x, yz := tagsetOf("x"), tagsetOf("y", "z")
+
+	// commutative
+	h1, l1 := unionKey(xy, z)
+	h2, l2 := unionKey(z, xy)
+	require.Equal(t, h1, h2)
+	require.Equal(t, l1, l2)
+
+	// distinct pairs have distinct keys, even if the XOR of their hashes
+	// is the same; {x,y} ∪ {y} and {x,z} ∪ {z} have different unions
+	h3, l3 := unionKey(x, yz)
+	require.False(t, h1 == h3 && l1 == l3)
+	h4, l4 := unionKey(xy, tagsetOf("y"))
+	h5, l5 := unionKey(tagsetOf("x", "z"), z)
+	require.False(t, h4 == h5 && l4 == l5)
+}
+
+func TestUnionCacheRotation(t *testing.T) {
+	c := newUnionCache(10)
+	ts := tagsetOf("a")
+
+	for i := 0; i < 100; i++ {
+		c.insert(uint64(i), uint64(i), ts)
+		require.LessOrEqual(t, c.len(), 20)
+	}
+
+	// recent entries are present, old entries are not
+	require.Equal(t, ts, c.get(99, 99))
+	require.Nil(t, c.get(0, 0))
+
+	// a hit in the previous generation is promoted to the current one
+	require.Nil(t, c.cur.get(85, 85))
+	require.Equal(t, ts, c.get(85, 85))
+	require.Equal(t, ts, c.cur.get(85, 85))
+}
+
+func TestInternFoundryUnionCache(t *testing.T) {
+	f, err := NewInternFoundry(WithUnionCacheSize(100))
+	require.NoError(t, err)
+
+	host := f.Parse(idFoundry, []byte("host:a,env:prod"))
+	tagger := f.Parse(idFoundry, []byte("service:web,env:prod"))
+
+	u1 := f.Union(host, tagger)
+	u2 := f.Union(tagger, host)
+	u3 := f.Union(host, tagger)
+	require.True(t, u1 == u2)
+	require.True(t, u1 == u3)
+	require.Equal(t, uint64(3), f.Unions)
+	require.Equal(t, uint64(1), f.UnionMisses)
+
+	// disjoint unions share the cache
+	other := f.Parse(idFoundry, []byte("x,y"))
+	d1 := f.DisjointUnion(host, other)
+	require.True(t, d1 == f.Union(other, host))
+	require.Equal(t, uint64(5), f.Unions)
+	require.Equal(t, uint64(2), f.UnionMisses)
+
+	// unions with the empty set are not counted
+	empty := f.Parse(idFoundry, []byte{})
+	require.True(t, host == f.Union(host, empty))
+	require.Equal(t, uint64(5), f.Unions)
+}
+
+func TestInternFoundryUnionCacheBounded(t *testing.T) {
+	f, err := NewInternFoundry(WithUnionCacheSize(50))
+	require.NoError(t, err)
+
+	base := f.Parse(idFoundry, []byte("a"))
+	for i := 0; i < 1000; i++ {
+		f.Union(base, f.Parse(idFoundry, []byte(fmt.Sprintf("x:%d", i))))
+	}
+	require.LessOrEqual(t, f.unions.len(), 100)
+	require.Equal(t, uint64(1000), f.UnionMisses)
+}
+
+func TestInternFoundryUnionCacheDisabled(t *testing.T) {
+	f, err := NewInternFoundry(WithUnionCacheSize(0))
+	require.NoError(t, err)
+	require.Nil(t, f.unions)
+
+	// the cache is disabled by default
+	f, err = NewInternFoundry()
+	require.NoError(t, err)
+	require.Nil(t, f.unions)
+
+	ts1 := f.Parse(idFoundry, []byte("a"))
+	ts2 := f.Parse(idFoundry, []byte("b"))
+	require.False(t, f.Union(ts1, ts2) == f.Union(ts1, ts2))
+	require.Equal(t, uint64(0), f.Unions)
+}
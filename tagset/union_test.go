@@ -0,0 +1,134 @@
+package tagset
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/djmitche/tagset/ident"
+	"github.com/djmitche/tagset/loadgen"
+	"github.com/stretchr/testify/require"
+)
+
+// legacyUnion is the implementation of Union from before tags were kept
+// sorted, which compares every pair of tags.  It is retained for comparison
+// in benchmarks, and produces an unsorted TagSet.  Like Union, it leaves the
+// serialization to be computed lazily, so that benchmarks compare only the
+// union itself.
+func legacyUnion(ts1 *TagSet, ts2 *TagSet) *TagSet {
+	// ensure t2 is smaller than t1
+	t1size, t2size := ts1.size, ts2.size
+	if t1size < t2size {
+		ts1, ts2 = ts2, ts1
+		t1size, t2size = t2size, t1size
+	}
+
+	hashH := ts1.hashH
+	hashL := ts1.hashL
+	clone := make([]ident.Ident, 0, t1size+t2size)
+	clone = append(clone, ts1.tags...)
+
+	// insert non-duplicate tags from ts2, updating the hash
+Outer:
+	for _, t2 := range ts2.tags {
+		for _, t1 := range ts1.tags {
+			if t1.Equals(t2) {
+				continue Outer
+			}
+		}
+		hashH ^= t2.HashH()
+		hashL ^= t2.HashL()
+		clone = append(clone, t2)
+	}
+
+	return &TagSet{
+		size:  len(clone),
+		tags:  clone,
+		hashH: hashH,
+		hashL: hashL,
+	}
+}
+
+// tagsetRange creates a TagSet containing tags `tag:<first>` through
+// `tag:<first+n-1>`
+func tagsetRange(first, n int) *TagSet {
+	idents := make([]ident.Ident, n)
+	for i := range idents {
+		idents[i] = idFoundry.Ident([]byte(fmt.Sprintf("tag:%d", first+i)))
+	}
+	return (&NullFoundry{}).NewWithoutDuplicates(idents)
+}
+
+func TestUnionMatchesLegacy(t *testing.T) {
+	f := &NullFoundry{}
+	for _, n := range []int{0, 1, 5, 50} {
+		for _, overlap := range []int{0, n / 2, n} {
+			ts1 := tagsetRange(0, n)
+			ts2 := tagsetRange(n-overlap, n)
+
+			exp := legacyUnion(ts1, ts2)
+			got := f.Union(ts1, ts2)
+			require.Equal(t, exp.HashH(), got.HashH())
+			require.Equal(t, exp.HashL(), got.HashL())
+			require.Equal(t, exp.Len(), got.Len())
+			require.ElementsMatch(t, exp.Strings(), got.Strings())
+		}
+	}
+}
+
+func BenchmarkUnion(b *testing.B) {
+	f := &NullFoundry{}
+	for _, n := range []int{4, 16, 64} {
+		// ts1 and ts2 overlap by half; ts1 and ts3 are disjoint
+		ts1 := tagsetRange(0, n)
+		ts2 := tagsetRange(n/2, n)
+		ts3 := tagsetRange(n, n)
+
+		b.Run(fmt.Sprintf("legacy/n=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchTS = legacyUnion(ts1, ts2)
+			}
+		})
+		b.Run(fmt.Sprintf("merge/n=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchTS = f.Union(ts1, ts2)
+			}
+		})
+		b.Run(fmt.Sprintf("disjoint/n=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchTS = f.DisjointUnion(ts1, ts3)
+			}
+		})
+	}
+}
+
+// BenchmarkUnionDSD compares legacyUnion and Union on random pairs of
+// tagsets like those a typical DogStatsD client sends, as the root package's
+// Benchmark.*FoundryUnion benchmarks use.
+func BenchmarkUnionDSD(b *testing.B) {
+	const tagsetCount = 100
+	f := &NullFoundry{}
+	lines := loadgen.DSDTagLineGenerator().GetLines()
+	tagsets := make([]*TagSet, 0, tagsetCount)
+	for i := 0; i < tagsetCount; i++ {
+		tagsets = append(tagsets, f.Parse(idFoundry, <-lines))
+	}
+
+	for _, bench := range []struct {
+		name  string
+		union func(*TagSet, *TagSet) *TagSet
+	}{
+		{"legacy", legacyUnion},
+		{"merge", f.Union},
+	} {
+		union := bench.union
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchTS = union(tagsets[i%tagsetCount], tagsets[(i*7+3)%tagsetCount])
+			}
+		})
+	}
+}